@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// The result of checking the password of an account against Have I Been Pwned (see "BreachChecker").
+type auditResult struct {
+	ID string `json:"id"`
+	// Identifies the checked password, so that the result is discarded once the password
+	// has been changed (see "pwFingerprint()")
+	Fingerprint string `json:"fingerprint"`
+	// How many times the password has appeared in a data breach
+	Count   uint32    `json:"count"`
+	Checked time.Time `json:"checked"`
+}
+
+// The cached results of the last audit of the active vault by account ID (see "loadAuditCache()").
+// They are used to flag compromised accounts in the accounts table.
+var auditResults = map[string]auditResult{}
+
+// Returns the path of the audit cache of the active vault. It is located next to the vault and
+// encrypted using the same key, as it reveals which accounts use compromised passwords.
+func auditPath() string {
+	return vaultPath + ".audit"
+}
+
+// Returns a short fingerprint of the given password. The fingerprint is only stored within the
+// encrypted audit cache and can't be used to query Have I Been Pwned.
+func pwFingerprint(pw string) string {
+	sum := sha256.Sum256([]byte(pw))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// Returns whether the result still applies to the given account, i.e. its password hasn't
+// been changed since it has been checked.
+func (r auditResult) current(a account) bool {
+	return r.ID == a.ID && r.Fingerprint == pwFingerprint(a.Pw)
+}
+
+// Returns whether the result is older than allowed by the user settings (see "auditSettings").
+func (r auditResult) stale() bool {
+	maxAge := userSettings.Audit.MaxAgeDays
+
+	return maxAge > 0 && time.Now().After(r.Checked.AddDate(0, 0, maxAge))
+}
+
+// Decrypts the audit cache of the active vault into "auditResults". A cache which doesn't
+// exist is treated as an empty cache. If the cache can't be read, it is cleared.
+func loadAuditCache() error {
+	results := []auditResult{}
+	auditResults = map[string]auditResult{}
+
+	if err := readEncryptedJSON(auditPath(), &results); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		auditResults[r.ID] = r
+	}
+
+	return nil
+}
+
+// Encrypts "auditResults" and writes them to the audit cache of the active vault.
+// Results of accounts which no longer exist are dropped.
+func saveAuditCache(accounts []account) error {
+	results := []auditResult{}
+
+	for _, id := range slices.Sorted(maps.Keys(auditResults)) {
+		if accountIndex(accounts, id) >= 0 {
+			results = append(results, auditResults[id])
+		}
+	}
+
+	return writeEncryptedJSON(auditPath(), results)
+}
+
+// Returns the cached number of breaches of the given account's password. The second return
+// value is false if the password hasn't been checked since it has last been changed.
+func breachCount(a account) (uint32, bool) {
+	r, ok := auditResults[a.ID]
+	if !ok || !r.current(a) {
+		return 0, false
+	}
+
+	return r.Count, true
+}
+
+// Returns the accounts whose passwords have to be checked: those which haven't been checked
+// since their password has been changed and those whose result is stale. If refresh is true,
+// all accounts are returned. Accounts without a password are skipped.
+func pendingAudits(accounts []account, refresh bool) []account {
+	pending := []account{}
+
+	for _, a := range accounts {
+		if len(a.Pw) == 0 {
+			continue
+		}
+
+		if r, ok := auditResults[a.ID]; refresh || !ok || !r.current(a) || r.stale() {
+			pending = append(pending, a)
+		}
+	}
+
+	return pending
+}
+
+// Checks the password of the given account against Have I Been Pwned. The result has to be
+// stored in "auditResults" and the cache has to be saved afterwards (see "saveAuditCache()").
+func auditAccount(a account) (auditResult, error) {
+	count, err := breachChecker.PwnedCount([]byte(a.Pw))
+	if err != nil {
+		return auditResult{}, err
+	}
+
+	return auditResult{ID: a.ID, Fingerprint: pwFingerprint(a.Pw), Count: count, Checked: now()}, nil
+}
+
+// Returns the service of the given account prefixed with a warning sign if its password has
+// appeared in a data breach according to the last audit.
+func flaggedService(a account) string {
+	if count, ok := breachCount(a); ok && count > 0 {
+		return "⚠ " + a.Service
+	}
+
+	return a.Service
+}
+
+// Returns a human-readable representation of the given number of breaches.
+func formatBreaches(count uint32) string {
+	switch count {
+
+	case 0:
+		return "Not found"
+
+	case 1:
+		return "Found once"
+	}
+
+	return "Found " + formatThousands(uint64(count)) + " times"
+}
+
+// Formats the given number using commas as thousands separators, e.g. "1,234,567".
+func formatThousands(n uint64) string {
+	digits := strconv.FormatUint(n, 10)
+	result := []byte{}
+
+	for i := range len(digits) {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			result = append(result, ',')
+		}
+
+		result = append(result, digits[i])
+	}
+
+	return string(result)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The layout of the timestamps within the names of the snapshots. It sorts lexically.
+const SNAPSHOT_TIME_LAYOUT = "20060102T150405.000000000Z"
+
+// An encrypted snapshot of a previous generation of the accounts file.
+type snapshot struct {
+	Path string
+	Time time.Time
+	Size int64
+}
+
+// Returns whether the given string is a timestamp as used within the names of the snapshots.
+func isSnapshotTime(s string) bool {
+	_, err := time.Parse(SNAPSHOT_TIME_LAYOUT, s)
+	return err == nil
+}
+
+// Returns the path of the directory containing the snapshots of the accounts file.
+// It is located next to the accounts file.
+func backupsDir() string {
+	return filepath.Join(filepath.Dir(vaultPath), "backups")
+}
+
+// Returns all snapshots of the active vault, newest first. The names of the snapshots
+// consist of the name of the vault and the time at which the vault has been written.
+// If the backups directory does not exist, an empty slice is returned.
+func listSnapshots() ([]snapshot, error) {
+	entries, err := os.ReadDir(backupsDir())
+	if os.IsNotExist(err) {
+		return []snapshot{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	snapshots := []snapshot{}
+
+	for _, entry := range entries {
+		name, found := strings.CutPrefix(entry.Name(), vaultName()+"-")
+		if !found || !entry.Type().IsRegular() || !isSnapshotTime(name) {
+			continue
+		}
+
+		t, _ := time.Parse(SNAPSHOT_TIME_LAYOUT, name)
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		snapshots = append(snapshots, snapshot{
+			Path: filepath.Join(backupsDir(), entry.Name()),
+			Time: t,
+			Size: info.Size(),
+		})
+	}
+
+	slices.SortFunc(snapshots, func(a, b snapshot) int {
+		return b.Time.Compare(a.Time)
+	})
+
+	return snapshots, nil
+}
+
+// Copies the current accounts file (if any) into the backups directory. The name of the snapshot
+// contains the name of the vault and the time at which the accounts file has been written. Afterwards, old snapshots are
+// removed according to the configured retention policy (see "pruneSnapshots()").
+// Does nothing if snapshots have been disabled.
+func snapshotVault() error {
+	if userSettings.Backups.Count <= 0 {
+		return nil
+	}
+
+	info, err := os.Stat(vaultPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(vaultPath)
+	if err != nil {
+		return err
+	}
+
+	defer zero(&data)
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(backupsDir(), 0750); err != nil {
+		return err
+	}
+
+	name := vaultName() + "-" + info.ModTime().UTC().Format(SNAPSHOT_TIME_LAYOUT)
+
+	if err := writeFileAtomic(filepath.Join(backupsDir(), name), data); err != nil {
+		return err
+	}
+
+	return pruneSnapshots()
+}
+
+// Removes all snapshots exceeding the configured maximum count or age.
+func pruneSnapshots() error {
+	snapshots, err := listSnapshots()
+	if err != nil {
+		return err
+	}
+
+	policy := userSettings.Backups
+	maxAge := time.Duration(policy.MaxAgeDays) * 24 * time.Hour
+
+	for i, s := range snapshots {
+		if i >= policy.Count || (policy.MaxAgeDays > 0 && time.Since(s.Time) > maxAge) {
+			if err := os.Remove(s.Path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Decrypts the given snapshot and returns the accounts contained within it. As a snapshot
+// has been encrypted when it was saved, it can only be decrypted if the master password
+// has not been changed since then.
+func loadSnapshot(s snapshot) ([]account, error) {
+	encryptedData, err := readFile(s.Path)
+	if err != nil {
+		return nil, err
+	} else if len(encryptedData) == 0 {
+		return nil, errors.New("The snapshot is empty")
+	}
+
+	data, err := decrypt(encryptedData)
+	if err != nil {
+		return nil, fmt.Errorf("Could not decrypt the snapshot: %w", err)
+	}
+
+	defer zero(data)
+
+	accounts, err := decodeAccounts(*data)
+
+	// Snapshots of legacy vaults lack IDs, which are derived the same way as for the vault itself,
+	// so that unchanged accounts are matched by "diffAccounts()". The time of the snapshot is the
+	// time of the last modification of the vault when the snapshot has been taken.
+	migrateAccounts(accounts, s.Time)
+
+	return accounts, err
+}
+
+// The differences between two generations of the accounts file.
+type accountDiff struct {
+	Added   []account
+	Removed []account
+	Changed []account
+}
+
+// Compares the current accounts with the accounts of a snapshot. The result describes what
+// would happen if the snapshot was restored: accounts only contained within the snapshot are
+// added, accounts only contained within the current accounts are removed and accounts whose
+// content differs are changed. Accounts are identified by their ID.
+func diffAccounts(current []account, snap []account) accountDiff {
+	diff := accountDiff{}
+
+	currentByKey := map[string]account{}
+	for _, a := range current {
+		currentByKey[a.ID] = a
+	}
+
+	snapByKey := map[string]account{}
+	for _, a := range snap {
+		snapByKey[a.ID] = a
+	}
+
+	for _, a := range snap {
+		c, found := currentByKey[a.ID]
+
+		if !found {
+			diff.Added = append(diff.Added, a)
+		} else if !sameContent(c, a) {
+			diff.Changed = append(diff.Changed, a)
+		}
+	}
+
+	for _, a := range current {
+		if _, found := snapByKey[a.ID]; !found {
+			diff.Removed = append(diff.Removed, a)
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,770 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// The exit codes of the CLI subcommands (see "runCommand()").
+const (
+	EXIT_OK        = 0
+	EXIT_ERROR     = 1
+	EXIT_USAGE     = 2
+	EXIT_NOT_FOUND = 3
+	EXIT_AMBIGUOUS = 4
+	EXIT_WRONG_KEY = 5
+)
+
+// The usage of the CLI subcommands which is appended to the usage of the flags.
+const COMMANDS_USAGE = `Commands:
+  list                 List all entries
+  get <service|id>     Print an entry
+    --field <field>      Only print the given field (id, service, description, notes, user or pw)
+  add <service>        Add an entry, the password is read from stdin
+    --user, --description, --notes <text>
+    --generate           Generate the password instead
+  edit <service|id>    Change an entry
+    --set-service, --set-description, --set-notes, --set-user <text>
+    --set-pw             Read the new password from stdin
+    --generate           Generate a new password
+  rm <service|id>      Move an entry to the trash (restore it from within the TUI)
+  journal              Print who changed which entries and when
+    --entry <service|id> Only print the changes of the given entry
+  audit                Check the passwords of all entries against Have I Been Pwned
+    --refresh            Check all passwords again, not only new, changed and outdated ones
+    --reuse              Print the entries sharing the same or similar passwords instead
+  strength             Estimate how hard a password is to guess, the password is read from stdin
+    --entry <service|id> Check the password of the given entry instead
+  generate             Print a generated password
+    --no-check           Don't check the password using Have I Been Pwned
+    --length <n>         Generate exactly n characters
+    --min-length, --max-length <n>
+    --min-lower, --min-upper, --min-digits, --min-symbols <n>
+    --symbols <chars>    The symbols to use (--symbols= disables symbols)
+    --exclude-look-alikes
+                         Don't use characters which are easily confused (e.g. l, 1 and I)
+    --max-repeat <n>     Allow at most n identical characters in a row (0 allows any number)
+    --passphrase         Generate a passphrase of random words instead (--passphrase=false
+                         generates random characters)
+    --words <n>          The number of words of the passphrase
+    --separator <text>   The separator between the words (--separator= joins the words)
+    --capitalize <mode>  none, first (first letter of every word), all or random
+    --add-digits, --add-symbols <n>
+                         Append n random digits or symbols to random words of the passphrase
+
+Generated passwords follow the generator policy of the settings unless it is overridden
+by the flags above (generate) or by the policy of the entry (edit --generate).
+
+list, get, journal and audit print a table unless --output json or --output tsv is set.
+Passwords are masked unless --reveal is set. strength prints a summary unless --output json is set.
+
+Entries are identified by their ID (or the first 8 characters of it) or their service
+(case-insensitive). If several entries share the same service, use --user <user>
+to select one of them (get, edit, rm and strength).
+
+Secrets are read from stdin. If stdin is a terminal, they are prompted for. Otherwise,
+the first line contains the master password (only if the vault requires one) and the
+next line contains the password of the entry (add and edit --set-pw only).
+
+Exit codes: 0 success, 1 error, 2 invalid usage, 3 entry not found,
+4 several entries match, 5 wrong master password.
+`
+
+// Reads secrets (the master password and passwords of entries) either by prompting the user
+// if stdin is a terminal or line by line from stdin otherwise.
+type secretReader struct {
+	terminal bool
+	reader   *bufio.Reader
+}
+
+// Returns a new secret reader reading from stdin.
+func newSecretReader() *secretReader {
+	terminal := false
+
+	if fi, err := os.Stdin.Stat(); err == nil {
+		terminal = fi.Mode()&os.ModeCharDevice != 0
+	}
+
+	return &secretReader{terminal: terminal, reader: bufio.NewReader(os.Stdin)}
+}
+
+// Reads the next secret. The given title and placeholder are shown if the user is prompted.
+// If confirm is true, the user has to enter the secret twice. The trailing line break is
+// not part of the secret.
+func (sr *secretReader) read(title string, placeholder string, confirm bool) ([]byte, error) {
+	if sr.terminal {
+		var (
+			secret []byte
+			ok     bool
+		)
+
+		if confirm {
+			secret, ok = chooseMasterPw(title)
+		} else {
+			p := newPwPrompt(title, "")
+			p.input.Placeholder = placeholder
+
+			secret, ok = runPwPrompt(p)
+		}
+
+		if !ok {
+			return nil, errors.New("Cancelled")
+		}
+
+		return secret, nil
+	}
+
+	line, err := sr.reader.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	secret := []byte(strings.TrimRight(string(line), "\r\n"))
+	zero(&line)
+
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("Expected the %s on stdin", strings.ToLower(placeholder))
+	}
+
+	return secret, nil
+}
+
+// Supplies the master password of the active vault if it requires one (see "needsMasterPw()").
+// Vaults which don't exist yet only require a master password if they are going to be written.
+func unlockForCommand(sr *secretReader, write bool) error {
+	encryptedData, err := readFile(vaultPath)
+	if err != nil {
+		return err
+	}
+
+	exists, required := len(encryptedData) > 0, needsMasterPw(encryptedData)
+	zero(&encryptedData)
+
+	if !required || (!exists && !write) {
+		return nil
+	}
+
+	if exists {
+		masterPw, err = sr.read("Enter your master password", "Master password", false)
+	} else {
+		masterPw, err = sr.read("Choose a master password for your new vault", "Master password", true)
+	}
+
+	return err
+}
+
+// Loads the accounts of the active vault. Vaults which could only be decoded partially
+// (see "ErrUndecodable") can be read, but never be written.
+func loadForCommand(sr *secretReader, write bool) (*[]account, error) {
+	if err := unlockForCommand(sr, write); err != nil {
+		return nil, err
+	}
+
+	accounts, err := getAllAccounts()
+
+	if errors.Is(err, ErrUndecodable) && !write {
+		fmt.Fprintln(os.Stderr, "Warning:", err)
+		return accounts, nil
+	}
+
+	return accounts, err
+}
+
+// Returns the index of the account with the given ID (or the first characters of its ID as shown
+// in the accounts table, see "SHORT_ID_LENGTH"). Otherwise, returns the index of the only account
+// of the given service (case-insensitive). If user is not nil, the user of the account has to
+// match as well. If several accounts match, an error wrapping "ErrAmbiguous" is returned.
+func findAccount(accounts []account, service string, user *string) (int, error) {
+	userMatches := func(a account) bool {
+		return user == nil || strings.EqualFold(a.User, *user)
+	}
+
+	matches := []int{}
+
+	for i, a := range accounts {
+		if a.ID == service && userMatches(a) {
+			return i, nil
+		}
+
+		if len(service) == SHORT_ID_LENGTH && strings.HasPrefix(a.ID, service) && userMatches(a) {
+			matches = append(matches, i)
+		}
+	}
+
+	if len(matches) == 1 {
+		return matches[0], nil
+	} else if len(matches) > 1 {
+		return -1, fmt.Errorf("%w: %d entries whose ID starts with %q, use the full ID", ErrAmbiguous, len(matches), service)
+	}
+
+	for i, a := range accounts {
+		if strings.EqualFold(a.Service, service) && userMatches(a) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+
+	case 0:
+		return -1, fmt.Errorf("%w: %q", ErrNotFound, service)
+
+	case 1:
+		return matches[0], nil
+	}
+
+	return -1, fmt.Errorf("%w: %d entries of %q, use --user to select one of them", ErrAmbiguous, len(matches), service)
+}
+
+// Returns the exit code corresponding to the given error.
+func exitCode(err error) int {
+	switch {
+
+	case err == nil:
+		return EXIT_OK
+
+	case errors.Is(err, ErrNotFound):
+		return EXIT_NOT_FOUND
+
+	case errors.Is(err, ErrAmbiguous):
+		return EXIT_AMBIGUOUS
+
+	case errors.Is(err, ErrWrongKey), errors.Is(err, ErrNoMasterPw):
+		return EXIT_WRONG_KEY
+	}
+
+	return EXIT_ERROR
+}
+
+// An optional string flag which records whether it has been set.
+type optionalString struct {
+	value *string
+}
+
+func (o *optionalString) String() string {
+	if o.value == nil {
+		return ""
+	}
+
+	return *o.value
+}
+
+func (o *optionalString) Set(value string) error {
+	o.value = &value
+	return nil
+}
+
+// Parses the given arguments of a subcommand. Unlike [flag.FlagSet.Parse], flags may follow
+// positional arguments (e.g. "get github --field pw"). Returns the positional arguments.
+func parseCommandArgs(fs *flag.FlagSet, args []string, positional int) ([]string, error) {
+	rest := []string{}
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		if fs.NArg() == 0 {
+			break
+		}
+
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if len(rest) != positional {
+		return nil, fmt.Errorf("Expected %d argument(s), got %d", positional, len(rest))
+	}
+
+	return rest, nil
+}
+
+// Runs the given subcommand and returns the exit code (see "COMMANDS_USAGE").
+func runCommand(args []string) int {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var run func(positional []string) error
+
+	positional := 0
+	sr := newSecretReader()
+
+	switch args[0] {
+
+	case "list":
+		output := addOutputFlags(fs)
+
+		run = func(_ []string) error {
+			return listCommand(sr, output)
+		}
+
+	case "get":
+		user := &optionalString{}
+		fs.Var(user, "user", "")
+		field := fs.String("field", "", "")
+		output := addOutputFlags(fs)
+
+		positional = 1
+		run = func(positional []string) error {
+			return getCommand(sr, positional[0], user.value, *field, output)
+		}
+
+	case "add":
+		user := fs.String("user", "", "")
+		description := fs.String("description", "", "")
+		notes := fs.String("notes", "", "")
+		generate := fs.Bool("generate", false, "")
+
+		positional = 1
+		run = func(positional []string) error {
+			return addCommand(sr, account{
+				Service:     positional[0],
+				Description: *description,
+				Notes:       *notes,
+				User:        *user,
+			}, *generate)
+		}
+
+	case "edit":
+		user := &optionalString{}
+		fs.Var(user, "user", "")
+
+		changes := map[string]*optionalString{}
+
+		for _, name := range []string{"set-service", "set-description", "set-notes", "set-user"} {
+			changes[name] = &optionalString{}
+			fs.Var(changes[name], name, "")
+		}
+
+		setPw := fs.Bool("set-pw", false, "")
+		generate := fs.Bool("generate", false, "")
+
+		positional = 1
+		run = func(positional []string) error {
+			return editCommand(sr, positional[0], user.value, changes, *setPw, *generate)
+		}
+
+	case "rm":
+		user := &optionalString{}
+		fs.Var(user, "user", "")
+
+		positional = 1
+		run = func(positional []string) error {
+			return rmCommand(sr, positional[0], user.value)
+		}
+
+	case "journal":
+		entry := fs.String("entry", "", "")
+		format := outputFormat(OUTPUT_TABLE)
+		fs.Var(&format, "output", "")
+
+		run = func(_ []string) error {
+			return journalCommand(sr, *entry, format)
+		}
+
+	case "audit":
+		refresh := fs.Bool("refresh", false, "")
+		reuse := fs.Bool("reuse", false, "")
+		format := outputFormat(OUTPUT_TABLE)
+		fs.Var(&format, "output", "")
+
+		run = func(_ []string) error {
+			if *reuse {
+				return reuseCommand(sr, format)
+			}
+
+			return auditCommand(sr, *refresh, format)
+		}
+
+	case "strength":
+		entry := fs.String("entry", "", "")
+		user := &optionalString{}
+		fs.Var(user, "user", "")
+		format := outputFormat(OUTPUT_TABLE)
+		fs.Var(&format, "output", "")
+
+		run = func(_ []string) error {
+			return strengthCommand(sr, *entry, user.value, format)
+		}
+
+	case "generate":
+		noCheck := fs.Bool("no-check", false, "")
+		policy := addPolicyFlags(fs, userSettings.Generator)
+
+		run = func(_ []string) error {
+			p, err := policy()
+			if err != nil {
+				return err
+			}
+
+			return generateCommand(*noCheck, p)
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", args[0], COMMANDS_USAGE)
+		return EXIT_USAGE
+	}
+
+	rest, err := parseCommandArgs(fs, args[1:], positional)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n\n%s", args[0], err, COMMANDS_USAGE)
+		return EXIT_USAGE
+	}
+
+	err = run(rest)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+	}
+
+	return exitCode(err)
+}
+
+// Prints all entries in the given output format.
+func listCommand(sr *secretReader, output *outputOptions) error {
+	accounts, err := loadForCommand(sr, false)
+	if err != nil {
+		return err
+	}
+
+	return output.printAccounts(*accounts)
+}
+
+// Prints an entry in the given output format or only the given field of an entry.
+// Printing the pw field reveals the password regardless of the --reveal flag.
+func getCommand(sr *secretReader, service string, user *string, field string, output *outputOptions) error {
+	accounts, err := loadForCommand(sr, false)
+	if err != nil {
+		return err
+	}
+
+	index, err := findAccount(*accounts, service, user)
+	if err != nil {
+		return err
+	}
+
+	a := (*accounts)[index]
+
+	switch field {
+
+	case "":
+		return output.printAccount(a)
+
+	case "id":
+		fmt.Println(a.ID)
+
+	case "service":
+		fmt.Println(a.Service)
+
+	case "description":
+		fmt.Println(a.Description)
+
+	case "notes":
+		fmt.Println(a.Notes)
+
+	case "user":
+		fmt.Println(a.User)
+
+	case "pw":
+		fmt.Println(a.Pw)
+
+	default:
+		return fmt.Errorf("Unknown field %q", field)
+	}
+
+	return nil
+}
+
+// Adds the given account to the vault. The password is either generated or read from stdin.
+func addCommand(sr *secretReader, a account, generate bool) error {
+	accounts, err := loadForCommand(sr, true)
+	if err != nil {
+		return err
+	}
+
+	if _, err := findAccount(*accounts, a.Service, &a.User); err == nil {
+		return fmt.Errorf("An entry of %q with the user %q already exists, use edit instead", a.Service, a.User)
+	}
+
+	pw, err := newEntryPw(sr, a.Service, generate, userSettings.Generator)
+	if err != nil {
+		return err
+	}
+
+	a.Pw = string(pw)
+	zero(&pw)
+
+	a.ID = newAccountID()
+	a.Created = now()
+	a.Modified = a.Created
+
+	*accounts = append(*accounts, a)
+
+	return saveAndJournal(accounts, newJournalEntry(nil, &a, ""))
+}
+
+// Changes the given fields of an entry. The password is changed if setPw (read from stdin)
+// or generate is true.
+func editCommand(sr *secretReader, service string, user *string, changes map[string]*optionalString, setPw bool, generate bool) error {
+	accounts, err := loadForCommand(sr, true)
+	if err != nil {
+		return err
+	}
+
+	index, err := findAccount(*accounts, service, user)
+	if err != nil {
+		return err
+	}
+
+	a := &(*accounts)[index]
+	previous := *a
+
+	for name, field := range map[string]*string{
+		"set-service":     &a.Service,
+		"set-description": &a.Description,
+		"set-notes":       &a.Notes,
+		"set-user":        &a.User,
+	} {
+		if changes[name].value != nil {
+			*field = *changes[name].value
+		}
+	}
+
+	if setPw || generate {
+		pw, err := newEntryPw(sr, a.Service, generate, policyOf(*a))
+		if err != nil {
+			return err
+		}
+
+		a.Pw = string(pw)
+		zero(&pw)
+	}
+
+	// Nothing has changed, hence the vault, its .bak file and its snapshots are left untouched
+	if sameContent(*a, previous) {
+		return nil
+	}
+
+	recordPwChange(previous, a)
+	a.Modified = now()
+
+	return saveAndJournal(accounts, newJournalEntry(&previous, a, ""))
+}
+
+// Moves an entry from the vault into its trash (see "trash.go").
+func rmCommand(sr *secretReader, service string, user *string) error {
+	accounts, err := loadForCommand(sr, true)
+	if err != nil {
+		return err
+	}
+
+	index, err := findAccount(*accounts, service, user)
+	if err != nil {
+		return err
+	}
+
+	removed := (*accounts)[index]
+
+	if err := trashAccount(removed); err != nil {
+		return err
+	}
+
+	*accounts = slices.Delete(*accounts, index, index+1)
+
+	return saveAndJournal(accounts, newJournalEntry(&removed, nil, "Moved to the trash"))
+}
+
+// Prints the journal of the vault in the given output format, oldest change first. If entry is
+// not empty, only the changes of the entry with the given ID (or the first characters of it) or
+// service are printed, including entries which have been deleted since.
+func journalCommand(sr *secretReader, entry string, format outputFormat) error {
+	if _, err := loadForCommand(sr, false); err != nil {
+		return err
+	}
+
+	journal, err := readJournal()
+	if err != nil {
+		return err
+	}
+
+	if len(entry) > 0 {
+		journal = slices.DeleteFunc(journal, func(e journalEntry) bool {
+			return e.ID != entry && !(len(entry) == SHORT_ID_LENGTH && strings.HasPrefix(e.ID, entry)) &&
+				!strings.EqualFold(e.Service, entry)
+		})
+	}
+
+	return printJournal(journal, format)
+}
+
+// Checks the passwords of all entries which haven't been checked yet, have been changed or whose
+// results are outdated against Have I Been Pwned (see "audit.go") and prints the results.
+// If refresh is true, all passwords are checked again.
+func auditCommand(sr *secretReader, refresh bool, format outputFormat) error {
+	if userSettings.BreachCheck.Source == BREACH_SOURCE_NONE {
+		return ErrBreachCheckDisabled
+	}
+
+	accounts, err := loadForCommand(sr, false)
+	if err != nil {
+		return err
+	}
+
+	if err := loadAuditCache(); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: Could not read the previous results:", err)
+	}
+
+	for _, a := range pendingAudits(*accounts, refresh) {
+		r, checkErr := auditAccount(a)
+
+		// Without a connection, the remaining passwords can't be checked either
+		if checkErr != nil {
+			err = fmt.Errorf("Could not check the passwords against Have I Been Pwned: %w", checkErr)
+			break
+		}
+
+		auditResults[a.ID] = r
+	}
+
+	if saveErr := saveAuditCache(*accounts); saveErr != nil {
+		fmt.Fprintln(os.Stderr, "Warning: Could not save the results:", saveErr)
+	}
+
+	if printErr := printAudit(*accounts, format); printErr != nil {
+		return printErr
+	}
+
+	return err
+}
+
+// Prints the groups of entries which share the same or nearly the same password (see "findReuse()").
+// The passwords themselves are never printed.
+func reuseCommand(sr *secretReader, format outputFormat) error {
+	accounts, err := loadForCommand(sr, false)
+	if err != nil {
+		return err
+	}
+
+	return printReuse(findReuse(*accounts), format)
+}
+
+// Prints the estimated strength of a password (see "estimateStrength()") which is either
+// read from stdin or taken from the given entry.
+func strengthCommand(sr *secretReader, entry string, user *string, format outputFormat) error {
+	if len(entry) == 0 {
+		pw, err := sr.read("Enter the password to check", "Password", false)
+		if err != nil {
+			return err
+		}
+
+		defer zero(&pw)
+
+		return printStrength(estimateStrength(string(pw)), format)
+	}
+
+	accounts, err := loadForCommand(sr, false)
+	if err != nil {
+		return err
+	}
+
+	index, err := findAccount(*accounts, entry, user)
+	if err != nil {
+		return err
+	}
+
+	a := (*accounts)[index]
+
+	return printStrength(estimateStrength(a.Pw, a.Service, a.User), format)
+}
+
+// Registers the flags of the password generator policy (see "pwPolicy") which override the
+// given policy. The returned function returns the resulting policy once the flags have been parsed.
+func addPolicyFlags(fs *flag.FlagSet, base pwPolicy) func() (pwPolicy, error) {
+	p := base
+	length := fs.Int("length", 0, "")
+
+	fs.IntVar(&p.MinLength, "min-length", p.MinLength, "")
+	fs.IntVar(&p.MaxLength, "max-length", p.MaxLength, "")
+	fs.IntVar(&p.MinLower, "min-lower", p.MinLower, "")
+	fs.IntVar(&p.MinUpper, "min-upper", p.MinUpper, "")
+	fs.IntVar(&p.MinDigits, "min-digits", p.MinDigits, "")
+	fs.IntVar(&p.MinSymbols, "min-symbols", p.MinSymbols, "")
+	fs.StringVar(&p.Symbols, "symbols", p.Symbols, "")
+	fs.BoolVar(&p.ExcludeLookAlikes, "exclude-look-alikes", p.ExcludeLookAlikes, "")
+	fs.IntVar(&p.MaxRepeat, "max-repeat", p.MaxRepeat, "")
+
+	passphrase := fs.Bool("passphrase", p.Mode == PW_MODE_PASSPHRASE, "")
+	fs.IntVar(&p.Passphrase.Words, "words", p.Passphrase.Words, "")
+	fs.StringVar(&p.Passphrase.Separator, "separator", p.Passphrase.Separator, "")
+	fs.StringVar(&p.Passphrase.Capitalize, "capitalize", p.Passphrase.Capitalize, "")
+	fs.IntVar(&p.Passphrase.Digits, "add-digits", p.Passphrase.Digits, "")
+	fs.IntVar(&p.Passphrase.Symbols, "add-symbols", p.Passphrase.Symbols, "")
+
+	return func() (pwPolicy, error) {
+		if *length > 0 {
+			p.MinLength, p.MaxLength = *length, *length
+		}
+
+		p.Mode = PW_MODE_CHARACTERS
+
+		if *passphrase {
+			p.Mode = PW_MODE_PASSPHRASE
+		}
+
+		// Symbols can't be required if they have been disabled explicitly
+		if len(p.Symbols) == 0 && p.MinSymbols == base.MinSymbols {
+			p.MinSymbols = 0
+		}
+
+		if len(p.Symbols) == 0 && p.Passphrase.Symbols == base.Passphrase.Symbols {
+			p.Passphrase.Symbols = 0
+		}
+
+		return p, p.validate()
+	}
+}
+
+// Prints a password generated according to the given policy. Unless noCheck is true,
+// the password is checked using "isPwValid()" which requires an internet connection unless
+// the offline Pwned Passwords database has been configured (see "breachCheckSettings").
+func generateCommand(noCheck bool, policy pwPolicy) error {
+	var pw *[]byte
+	var err error
+
+	if noCheck {
+		pw, err = generatePwInternal(policy)
+	} else {
+		pw, err = generatePw(policy)
+	}
+
+	defer zero(pw)
+
+	if errors.Is(err, ErrGeneratorExhausted) {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("%w, use --no-check to skip the check", err)
+	}
+
+	fmt.Println(string(*pw))
+
+	return nil
+}
+
+// Returns a new password for the entry of the given service which is either generated
+// according to the given policy or read from stdin.
+func newEntryPw(sr *secretReader, service string, generate bool, policy pwPolicy) ([]byte, error) {
+	if !generate {
+		return sr.read(fmt.Sprintf("Enter the password of %q", service), "Password", false)
+	}
+
+	pw, err := generatePw(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return *pw, nil
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// The output formats of the commands printing entries (see the --output flag).
+const (
+	OUTPUT_TABLE = "table"
+	OUTPUT_JSON  = "json"
+	OUTPUT_TSV   = "tsv"
+)
+
+// The text shown instead of a password in the table format unless --reveal is set.
+const MASKED_PW = "••••••••"
+
+// An entry as printed by the list and get commands. This is the documented schema of the
+// JSON and TSV formats, hence fields must never be renamed or removed, only added.
+// The password is null (JSON) or empty (TSV) unless --reveal is set. The time the password has
+// last been copied is null (JSON) or empty (TSV) if it has never been used.
+type accountRecord struct {
+	Service     string     `json:"service"`
+	Description string     `json:"description"`
+	Notes       string     `json:"notes"`
+	User        string     `json:"user"`
+	Pw          *string    `json:"pw"`
+	ID          string     `json:"id"`
+	Created     time.Time  `json:"created"`
+	Modified    time.Time  `json:"modified"`
+	LastUsed    *time.Time `json:"lastUsed"`
+}
+
+// Returns the record of the given account. The password is only included if reveal is true.
+func newAccountRecord(a account, reveal bool) accountRecord {
+	r := accountRecord{
+		Service:     a.Service,
+		Description: a.Description,
+		Notes:       a.Notes,
+		User:        a.User,
+		ID:          a.ID,
+		Created:     a.Created,
+		Modified:    a.Modified,
+	}
+
+	if !a.LastUsed.IsZero() {
+		r.LastUsed = &a.LastUsed
+	}
+
+	if reveal {
+		r.Pw = &a.Pw
+	}
+
+	return r
+}
+
+// The output format set by the --output flag. Unknown formats are rejected while parsing the flags.
+type outputFormat string
+
+func (f *outputFormat) String() string {
+	return string(*f)
+}
+
+func (f *outputFormat) Set(value string) error {
+	switch value {
+
+	case OUTPUT_TABLE, OUTPUT_JSON, OUTPUT_TSV:
+		*f = outputFormat(value)
+		return nil
+	}
+
+	return fmt.Errorf("Unknown output format %q, expected %s, %s or %s", value, OUTPUT_JSON, OUTPUT_TSV, OUTPUT_TABLE)
+}
+
+// The options of the commands printing entries.
+type outputOptions struct {
+	format outputFormat
+	reveal bool
+}
+
+// Registers the --output and --reveal flags.
+func addOutputFlags(fs *flag.FlagSet) *outputOptions {
+	o := &outputOptions{format: OUTPUT_TABLE}
+
+	fs.Var(&o.format, "output", "")
+	fs.BoolVar(&o.reveal, "reveal", false, "")
+
+	return o
+}
+
+// Returns the password of the given record as shown in the table format.
+func (r accountRecord) tablePw() string {
+	if r.Pw == nil {
+		return MASKED_PW
+	}
+
+	return *r.Pw
+}
+
+// Escapes backslashes, tabs and line breaks, so that every record occupies exactly one line.
+func escapeTSV(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(value)
+}
+
+// Returns the fields of the given record in the order of the TSV columns.
+func (r accountRecord) tsvFields() []string {
+	pw, lastUsed := "", ""
+
+	if r.Pw != nil {
+		pw = *r.Pw
+	}
+
+	if r.LastUsed != nil {
+		lastUsed = r.LastUsed.Format(time.RFC3339)
+	}
+
+	return []string{
+		r.Service, r.Description, r.Notes, r.User, pw,
+		r.ID, r.Created.Format(time.RFC3339), r.Modified.Format(time.RFC3339), lastUsed,
+	}
+}
+
+// Prints the given accounts. JSON is printed as an array of records.
+func (o *outputOptions) printAccounts(accounts []account) error {
+	records := []accountRecord{}
+
+	for _, a := range accounts {
+		records = append(records, newAccountRecord(a, o.reveal))
+	}
+
+	switch o.format {
+
+	case OUTPUT_JSON:
+		return printJSON(records)
+
+	case OUTPUT_TSV:
+		fmt.Println("service\tdescription\tnotes\tuser\tpw\tid\tcreated\tmodified\tlastUsed")
+
+		for _, r := range records {
+			fields := r.tsvFields()
+
+			for i := range fields {
+				fields[i] = escapeTSV(fields[i])
+			}
+
+			fmt.Println(strings.Join(fields, "\t"))
+		}
+
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSERVICE\tUSER\tDESCRIPTION\tPW")
+
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.ID[:min(len(r.ID), SHORT_ID_LENGTH)], r.Service, r.User, r.Description, r.tablePw())
+	}
+
+	return w.Flush()
+}
+
+// Prints the given account. JSON is printed as a single record.
+func (o *outputOptions) printAccount(a account) error {
+	r := newAccountRecord(a, o.reveal)
+
+	switch o.format {
+
+	case OUTPUT_JSON:
+		return printJSON(r)
+
+	case OUTPUT_TSV:
+		return o.printAccounts([]account{a})
+	}
+
+	lastUsed := "never"
+
+	if r.LastUsed != nil {
+		lastUsed = r.LastUsed.Local().Format(time.DateTime)
+	}
+
+	fmt.Printf("id:          %s\nservice:     %s\ndescription: %s\nnotes:       %s\nuser:        %s\npw:          %s\ncreated:     %s\nmodified:    %s\nlast used:   %s\n",
+		r.ID, r.Service, r.Description, r.Notes, r.User, r.tablePw(),
+		r.Created.Local().Format(time.DateTime), r.Modified.Local().Format(time.DateTime), lastUsed)
+
+	return nil
+}
+
+// Prints the given journal entries. JSON is printed as an array of journal entries.
+func printJournal(journal []journalEntry, format outputFormat) error {
+	switch format {
+
+	case OUTPUT_JSON:
+		return printJSON(journal)
+
+	case OUTPUT_TSV:
+		fmt.Println("time\tauthor\taction\tid\tservice\tuser\tfields\tnote")
+
+		for _, e := range journal {
+			fields := []string{
+				e.Time.Format(time.RFC3339), e.Author, e.Action, e.ID,
+				e.Service, e.User, strings.Join(e.Fields, ","), e.Note,
+			}
+
+			for i := range fields {
+				fields[i] = escapeTSV(fields[i])
+			}
+
+			fmt.Println(strings.Join(fields, "\t"))
+		}
+
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tAUTHOR\tACTION\tID\tSERVICE\tUSER\tDETAILS")
+
+	for _, e := range journal {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.Local().Format(time.DateTime), e.Author, e.Action,
+			e.ID[:min(len(e.ID), SHORT_ID_LENGTH)], e.Service, e.User, journalDetails(e))
+	}
+
+	return w.Flush()
+}
+
+// The result of the breach check of an entry as printed by the audit command. This is the
+// documented schema of the JSON and TSV formats. The number of breaches and the time of the
+// check are null (JSON) or empty (TSV) if the password hasn't been checked since it has been changed.
+type auditRecord struct {
+	ID       string     `json:"id"`
+	Service  string     `json:"service"`
+	User     string     `json:"user"`
+	Breaches *uint32    `json:"breaches"`
+	Checked  *time.Time `json:"checked"`
+}
+
+// Prints the results of the breach check of the given accounts (see "audit.go").
+func printAudit(accounts []account, format outputFormat) error {
+	records := []auditRecord{}
+
+	for _, a := range accounts {
+		r := auditRecord{ID: a.ID, Service: a.Service, User: a.User}
+
+		if count, ok := breachCount(a); ok {
+			checked := auditResults[a.ID].Checked
+			r.Breaches, r.Checked = &count, &checked
+		}
+
+		records = append(records, r)
+	}
+
+	switch format {
+
+	case OUTPUT_JSON:
+		return printJSON(records)
+
+	case OUTPUT_TSV:
+		fmt.Println("id\tservice\tuser\tbreaches\tchecked")
+
+		for _, r := range records {
+			breaches, checked := "", ""
+
+			if r.Breaches != nil {
+				breaches = strconv.FormatUint(uint64(*r.Breaches), 10)
+				checked = r.Checked.Format(time.RFC3339)
+			}
+
+			fmt.Println(strings.Join([]string{escapeTSV(r.ID), escapeTSV(r.Service), escapeTSV(r.User), breaches, checked}, "\t"))
+		}
+
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSERVICE\tUSER\tBREACHES\tCHECKED")
+
+	for i, r := range records {
+		breaches, checked := "Not checked", ""
+
+		if len(accounts[i].Pw) == 0 {
+			breaches = "No password"
+		} else if r.Breaches != nil {
+			breaches = formatBreaches(*r.Breaches)
+			checked = r.Checked.Local().Format(time.DateTime)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.ID[:min(len(r.ID), SHORT_ID_LENGTH)], r.Service, r.User, breaches, checked)
+	}
+
+	return w.Flush()
+}
+
+// A group of entries sharing the same or similar passwords as printed by audit --reuse. This is
+// the documented schema of the JSON format. The TSV format prints one line per entry instead,
+// preceded by the number of its group.
+type reuseRecord struct {
+	Kind    string             `json:"kind"`
+	Reasons []string           `json:"reasons"`
+	Entries []reuseRecordEntry `json:"entries"`
+}
+
+type reuseRecordEntry struct {
+	ID      string `json:"id"`
+	Service string `json:"service"`
+	User    string `json:"user"`
+}
+
+// Prints the given groups of entries sharing the same or similar passwords (see "findReuse()").
+func printReuse(groups []reuseGroup, format outputFormat) error {
+	records := []reuseRecord{}
+
+	for _, g := range groups {
+		r := reuseRecord{Kind: g.Kind, Reasons: []string{}, Entries: []reuseRecordEntry{}}
+		r.Reasons = append(r.Reasons, g.Reasons...)
+
+		for _, a := range g.Accounts {
+			r.Entries = append(r.Entries, reuseRecordEntry{ID: a.ID, Service: a.Service, User: a.User})
+		}
+
+		records = append(records, r)
+	}
+
+	switch format {
+
+	case OUTPUT_JSON:
+		return printJSON(records)
+
+	case OUTPUT_TSV:
+		fmt.Println("group\tkind\treasons\tid\tservice\tuser")
+
+		for i, r := range records {
+			for _, e := range r.Entries {
+				fmt.Println(strings.Join([]string{strconv.Itoa(i + 1), r.Kind, strings.Join(r.Reasons, ","),
+					escapeTSV(e.ID), escapeTSV(e.Service), escapeTSV(e.User)}, "\t"))
+			}
+		}
+
+		return nil
+	}
+
+	if len(groups) == 0 {
+		fmt.Println("No entries share the same or similar passwords.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tKIND\tID\tSERVICE\tUSER")
+
+	for i, g := range groups {
+		for _, a := range g.Accounts {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", i+1, g.describe(), a.ID[:min(len(a.ID), SHORT_ID_LENGTH)], a.Service, a.User)
+		}
+	}
+
+	return w.Flush()
+}
+
+// Prints the estimated strength of a password. Neither format contains the password itself.
+func printStrength(s strength, format outputFormat) error {
+	switch format {
+
+	case OUTPUT_JSON:
+		return printJSON(s)
+
+	case OUTPUT_TSV:
+		fmt.Println("score\tguesses\tonlineThrottled\tonline\tofflineSlow\tofflineFast\twarning")
+		fmt.Printf("%d\t%g\t%g\t%g\t%g\t%g\t%s\n", s.Score, s.Guesses, s.CrackTimes[CRACK_ONLINE_THROTTLED],
+			s.CrackTimes[CRACK_ONLINE], s.CrackTimes[CRACK_OFFLINE_SLOW], s.CrackTimes[CRACK_OFFLINE_FAST], escapeTSV(s.Warning))
+
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Score:\t%d/4 (%s)\n", s.Score, scoreNames[s.Score])
+	fmt.Fprintf(w, "Guesses:\t%s\n", formatGuesses(s.Guesses))
+	fmt.Fprintf(w, "Online attack, throttled:\t%s\n", formatCrackTime(s.CrackTimes[CRACK_ONLINE_THROTTLED]))
+	fmt.Fprintf(w, "Online attack:\t%s\n", formatCrackTime(s.CrackTimes[CRACK_ONLINE]))
+	fmt.Fprintf(w, "Offline attack, slow hash:\t%s\n", formatCrackTime(s.CrackTimes[CRACK_OFFLINE_SLOW]))
+	fmt.Fprintf(w, "Offline attack, fast hash:\t%s\n", formatCrackTime(s.CrackTimes[CRACK_OFFLINE_FAST]))
+
+	if len(s.Warning) > 0 {
+		fmt.Fprintf(w, "Warning:\t%s\n", s.Warning)
+	}
+
+	for i, suggestion := range s.Suggestions {
+		label := ""
+
+		if i == 0 {
+			label = "Suggestions:"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\n", label, suggestion)
+	}
+
+	return w.Flush()
+}
+
+// Prints the given value as indented JSON.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
@@ -0,0 +1,39 @@
+package main
+
+import "errors"
+
+// The errors which can occur while reading or writing a vault. They are usually wrapped
+// together with some details, hence use [errors.Is] to check for them.
+var (
+	// The vault could not be decrypted, most likely because of a wrong master password
+	// or because it has been encrypted by another Windows account.
+	ErrWrongKey = errors.New("The vault could not be decrypted using the given key")
+	// The vault is truncated or otherwise damaged.
+	ErrCorruptVault = errors.New("The vault is corrupt")
+	// The vault has been written by a newer version of PwdMan.
+	ErrUnsupportedVersion = errors.New("Unsupported vault format version")
+	// The vault uses a cipher or key derivation function unknown to this version of PwdMan.
+	ErrUnsupportedCipher = errors.New("Unsupported cipher or key derivation function")
+	// The vault uses a backend which is not available on this platform (e.g. the DPAPI on Linux).
+	ErrUnsupportedPlatform = errors.New("The vault can't be opened on this platform")
+	// The vault requires a master password, but none has been supplied.
+	ErrNoMasterPw = errors.New("No master password has been supplied")
+	// The vault could be decrypted, but (some of) the accounts stored within it could not be decoded.
+	// The accounts which could be decoded are returned together with this error (see "decodeAccounts()").
+	ErrUndecodable = errors.New("The accounts stored in the vault could not be decoded")
+)
+
+// The change journal of the vault could not be written (see "saveAndJournal()"). The accounts
+// themselves have been saved nonetheless.
+var ErrJournal = errors.New("Saved, but the journal could not be written")
+
+// The errors which can occur while looking up an entry by its service (see "findAccount()").
+var (
+	// No entry matches.
+	ErrNotFound = errors.New("No such entry")
+	// Several entries match.
+	ErrAmbiguous = errors.New("Several entries match")
+)
+
+// No password satisfying the policy could be generated (see "generatePwInternal()").
+var ErrGeneratorExhausted = errors.New("Could not generate a password satisfying the policy")
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// The character classes used by the password generator (see "generatePwInternal()").
+const (
+	LOWER_CHARS = "abcdefghijklmnopqrstuvwxyz"
+	UPPER_CHARS = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	DIGIT_CHARS = "0123456789"
+	// The default set of symbols
+	SYMBOL_CHARS = "?+=!&/-_<>|"
+	// Characters which are easily confused with each other, excluded if "pwPolicy.ExcludeLookAlikes" is set
+	LOOK_ALIKE_CHARS = "Il1|O0o"
+)
+
+// The maximum number of passwords generated in order to find one which doesn't contain
+// repeated runs of characters (see "pwPolicy.MaxRepeat").
+const MAX_GENERATOR_ATTEMPTS = 1000
+
+// The policy of the password generator. The global policy is part of the user settings, but every
+// account can have its own policy (see "account.Policy"), e.g. if a service rejects some symbols.
+type pwPolicy struct {
+	// Whether passwords consist of random characters or random words (see "PW_MODE_CHARACTERS").
+	// If empty, random characters are used.
+	Mode string `json:"mode"`
+	// The minimum and maximum number of characters. The length is chosen randomly within this range.
+	MinLength int `json:"minLength"`
+	MaxLength int `json:"maxLength"`
+	// The minimum number of characters of each class
+	MinLower   int `json:"minLower"`
+	MinUpper   int `json:"minUpper"`
+	MinDigits  int `json:"minDigits"`
+	MinSymbols int `json:"minSymbols"`
+	// The symbols which may be used. If empty, no symbols are used.
+	Symbols string `json:"symbols"`
+	// Whether characters which are easily confused with each other (see "LOOK_ALIKE_CHARS") are excluded
+	ExcludeLookAlikes bool `json:"excludeLookAlikes"`
+	// The maximum number of identical characters in a row. 0 allows any number.
+	MaxRepeat int `json:"maxRepeat"`
+	// The settings of the passphrase mode. The length, the minimum numbers of characters and
+	// the maximum number of repeated characters don't apply to passphrases.
+	Passphrase passphrasePolicy `json:"passphrase"`
+}
+
+// Returns the policy used if neither the settings nor the account define a policy.
+func defaultPwPolicy() pwPolicy {
+	return pwPolicy{
+		Mode:       PW_MODE_CHARACTERS,
+		MinLength:  16,
+		MaxLength:  24,
+		MinLower:   1,
+		MinUpper:   1,
+		MinDigits:  1,
+		MinSymbols: 1,
+		Symbols:    SYMBOL_CHARS,
+		MaxRepeat:  2,
+		Passphrase: defaultPassphrasePolicy(),
+	}
+}
+
+// Returns the policy used to generate passwords for the given account: its own policy if it
+// has one, otherwise the global policy of the user settings.
+func policyOf(a account) pwPolicy {
+	if a.Policy != nil {
+		return *a.Policy
+	}
+
+	return userSettings.Generator
+}
+
+// Returns the characters of the given class, without the look-alike characters if they are excluded.
+func (p pwPolicy) chars(class string) string {
+	if !p.ExcludeLookAlikes {
+		return class
+	}
+
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(LOOK_ALIKE_CHARS, r) {
+			return -1
+		}
+
+		return r
+	}, class)
+}
+
+// A class of characters used by the password generator together with the minimum number of
+// characters of that class.
+type charClass struct {
+	chars string
+	min   int
+}
+
+// Returns the character classes of the policy.
+func (p pwPolicy) classes() []charClass {
+	return []charClass{
+		{p.chars(LOWER_CHARS), p.MinLower},
+		{p.chars(UPPER_CHARS), p.MinUpper},
+		{p.chars(DIGIT_CHARS), p.MinDigits},
+		{p.chars(p.Symbols), p.MinSymbols},
+	}
+}
+
+// Returns all characters which may be used by the policy.
+func (p pwPolicy) alphabet() string {
+	alphabet := ""
+
+	for _, c := range p.classes() {
+		alphabet += c.chars
+	}
+
+	return alphabet
+}
+
+// Returns an error if no password can be generated using the policy.
+func (p pwPolicy) validate() error {
+	for _, r := range p.Symbols {
+		if r < '!' || r > '~' || strings.ContainsRune(LOWER_CHARS+UPPER_CHARS+DIGIT_CHARS, r) {
+			return errors.New("The symbols must be printable ASCII characters other than letters and digits")
+		}
+	}
+
+	switch p.Mode {
+
+	case "", PW_MODE_CHARACTERS:
+
+	case PW_MODE_PASSPHRASE:
+		return p.validatePassphrase()
+
+	default:
+		return fmt.Errorf("Unknown generator mode %q, expected %q or %q", p.Mode, PW_MODE_CHARACTERS, PW_MODE_PASSPHRASE)
+	}
+
+	required := 0
+
+	for _, c := range p.classes() {
+		if c.min < 0 {
+			return errors.New("The minimum number of characters of a class must not be negative")
+		}
+
+		if c.min > 0 && len(c.chars) == 0 {
+			return errors.New("Symbols are required, but the set of symbols is empty")
+		}
+
+		required += c.min
+	}
+
+	switch {
+
+	case p.MinLength < 1 || p.MaxLength < p.MinLength:
+		return errors.New("The length must be at least 1 and the maximum length must not be less than the minimum length")
+
+	case required > p.MaxLength:
+		return fmt.Errorf("The minimum numbers of characters (%d in total) exceed the maximum length of %d", required, p.MaxLength)
+
+	case p.MaxRepeat < 0:
+		return errors.New("The maximum number of repeated characters must not be negative")
+
+	case p.MaxRepeat > 0 && len(p.alphabet()) < 2 && p.MaxLength > p.MaxRepeat:
+		return errors.New("At least two different characters are required to avoid repeated characters")
+	}
+
+	return nil
+}
+
+// Returns an error if no passphrase can be generated using the policy.
+func (p pwPolicy) validatePassphrase() error {
+	pp := p.Passphrase
+
+	switch {
+
+	case pp.Words < 1:
+		return errors.New("A passphrase must consist of at least one word")
+
+	case !slices.Contains(capitalizations, pp.Capitalize):
+		return fmt.Errorf("Unknown capitalization %q, expected one of %s", pp.Capitalize, strings.Join(capitalizations, ", "))
+
+	case pp.Digits < 0 || pp.Symbols < 0:
+		return errors.New("The number of digits and symbols must not be negative")
+
+	case pp.Symbols > 0 && len(p.chars(p.Symbols)) == 0:
+		return errors.New("Symbols are required, but the set of symbols is empty")
+
+	case strings.ContainsAny(pp.Separator, "\n\r\t"):
+		return errors.New("The separator must not contain line breaks or tabs")
+	}
+
+	return nil
+}
+
+// Returns whether the given password contains more identical characters in a row than allowed by the policy.
+func (p pwPolicy) hasRepeatedRun(pw []byte) bool {
+	if p.MaxRepeat <= 0 {
+		return false
+	}
+
+	run := 0
+
+	for i := range pw {
+		if i > 0 && pw[i] == pw[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+
+		if run > p.MaxRepeat {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,65 +2,234 @@ package main
 
 import (
 	"crypto/rand"
-	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
 	"math/big"
-	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
-	"strconv"
-	"strings"
+	"slices"
+	"time"
 )
 
 const FILE_MODE os.FileMode = 0640
 
+// The path of the vault used by older versions of PwdMan, relative to the current working directory.
+const LEGACY_VAULT_PATH = "accounts"
+
+// The master password supplied by the user. It is only used by the portable backend
+// in order to derive the key used by "encrypt()" and "decrypt()" (see "needsMasterPw()").
+var masterPw []byte
+
+// The absolute path of the accounts file (the vault), see "resolveVaultPath()".
+var vaultPath string
+
 // A struct containing information about a specific user account.
+// Every account is identified by a random UUID (see "newAccountID()") which never changes,
+// unlike its position within the accounts file. LastUsed is the time the password has last been
+// copied and is zero if it has never been used, it is kept in the usage file instead of the
+// vault (see "usagePath()"). History contains the previous passwords of the account, most recent
+// first (see "recordPwChange()"). Policy is the policy of the password generator used for this
+// account, nil if the global policy is used (see "policyOf()").
 type account struct {
-	Service     string `json:"service"`
-	Description string `json:"description"`
-	Notes       string `json:"notes"`
-	User        string `json:"user"`
-	Pw          string `json:"pw"`
+	ID          string           `json:"id"`
+	Service     string           `json:"service"`
+	Description string           `json:"description"`
+	Notes       string           `json:"notes"`
+	User        string           `json:"user"`
+	Pw          string           `json:"pw"`
+	Created     time.Time        `json:"created"`
+	Modified    time.Time        `json:"modified"`
+	LastUsed    time.Time        `json:"-"`
+	History     []pwHistoryEntry `json:"history,omitempty"`
+	Policy      *pwPolicy        `json:"policy,omitempty"`
+}
+
+// A previous password of an account and the time it has been replaced.
+type pwHistoryEntry struct {
+	Pw       string    `json:"pw"`
+	Replaced time.Time `json:"replaced"`
+}
+
+// Returns a new random (version 4) UUID identifying an account.
+func newAccountID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+
+	return formatUUID(id)
+}
+
+// Formats the given 16 bytes as a UUID (xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx).
+func formatUUID(id []byte) string {
+	h := hex.EncodeToString(id)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// Returns the current time as stored in the created and modified timestamps of an account.
+// The monotonic clock reading is stripped so that the timestamps compare equal after
+// they have been written to and read from disk.
+func now() time.Time {
+	return time.Now().UTC().Round(0)
+}
+
+// Assigns an ID and timestamps to the accounts written by older versions of PwdMan, which
+// are persisted by the next call to "saveAccountsToDisk()". Until then, the IDs are derived from
+// the position and the content of the accounts, so that they stay the same every time the
+// accounts file is read. The timestamps are set to the given time of the last modification
+// of the accounts file.
+func migrateAccounts(accounts []account, modTime time.Time) {
+	for i := range accounts {
+		a := &accounts[i]
+
+		if len(a.ID) == 0 {
+			h := sha256.Sum256(fmt.Appendf(nil, "%d\x00%s\x00%s\x00%s", i, a.Service, a.User, a.Description))
+
+			// Marks the UUID as version 8 (custom)
+			h[6] = h[6]&0x0f | 0x80
+			h[8] = h[8]&0x3f | 0x80
+
+			a.ID = formatUUID(h[:16])
+		}
+
+		if a.Created.IsZero() {
+			a.Created = modTime.UTC().Round(0)
+		}
+
+		if a.Modified.IsZero() {
+			a.Modified = a.Created
+		}
+	}
+}
+
+// Returns whether the given accounts have the same content (ignoring their IDs and timestamps).
+func sameContent(a account, b account) bool {
+	return a.Service == b.Service && a.Description == b.Description && a.Notes == b.Notes &&
+		a.User == b.User && a.Pw == b.Pw
+}
+
+// Adds the password of the given previous version of an account to the history of the edited
+// version if the password has been changed. The history is limited to the number of passwords
+// given in the user settings, dropping the oldest ones.
+func recordPwChange(previous account, edited *account) {
+	if previous.Pw == edited.Pw || len(previous.Pw) == 0 {
+		return
+	}
+
+	history := []pwHistoryEntry{{Pw: previous.Pw, Replaced: now()}}
+	history = append(history, previous.History...)
+
+	edited.History = history[:min(len(history), max(userSettings.History.Count, 0))]
+}
+
+// Returns the index of the account with the given ID or -1 if there is no such account.
+func accountIndex(accounts []account, id string) int {
+	return slices.IndexFunc(accounts, func(a account) bool {
+		return a.ID == id
+	})
 }
 
 // Returns a pointer to a slice of structs containing information about a specific account.
 // If the accounts file doesn't exist or is empty, then this function will return a pointer
 // to an empty slice of above mentioned structs.
-func getAllAccounts() *[]account {
+// Legacy (headerless) accounts files written by older versions of PwdMan can still be read
+// and will transparently be upgraded to the current vault format (see "VAULT_VERSION")
+// by the next call to "saveAccountsToDisk()".
+// If the accounts file could not be read or decrypted, an error wrapping one of the errors
+// defined in errors.go (e.g. "ErrWrongKey") is returned. If the decrypted accounts could not be
+// decoded completely, the decodable accounts are returned together with "ErrUndecodable".
+// Such a vault must not be saved as that would overwrite the undecodable accounts.
+func getAllAccounts() (*[]account, error) {
+	accounts := []account{}
+
+	encryptedData, err := readFile(vaultPath)
+	if err != nil || len(encryptedData) == 0 {
+		return &accounts, err
+	}
+
+	defer zero(&encryptedData)
+
+	data, err := decrypt(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	defer zero(data)
+
+	accounts, err = decodeAccounts(*data)
+
+	if fi, statErr := os.Stat(vaultPath); statErr == nil {
+		migrateAccounts(accounts, fi.ModTime())
+	}
+
+	applyUsage(accounts, *data)
+
+	return &accounts, err
+}
+
+// Decodes the given JSON data into a slice of accounts. If the data can't be decoded as a whole,
+// every entry is decoded on its own so that as many accounts as possible are recovered. In that
+// case, the recovered accounts are returned together with an error wrapping "ErrUndecodable".
+func decodeAccounts(data []byte) ([]account, error) {
 	accounts := []account{}
 
-	encryptedData := readFileRel("accounts")
-	if len(encryptedData) == 0 {
-		return &accounts
+	if err := json.Unmarshal(data, &accounts); err == nil {
+		return accounts, nil
+	}
+
+	entries := []json.RawMessage{}
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return []account{}, fmt.Errorf("%w (%v)", ErrUndecodable, err)
 	}
 
-	data := decrypt(encryptedData)
+	accounts = []account{}
 
-	json.Unmarshal(*data, &accounts)
+	for _, entry := range entries {
+		a := account{}
 
-	zero(data)
-	zero(&encryptedData)
+		if json.Unmarshal(entry, &a) == nil {
+			accounts = append(accounts, a)
+		}
+	}
 
-	return &accounts
+	return accounts, fmt.Errorf("%w (%d of %d entries are unreadable)", ErrUndecodable, len(entries)-len(accounts), len(entries))
 }
 
 // Takes in a pointer to a slice of account structs, encrypts the data in JSON format
 // and then writes the resulting bytes into the local, predefined accounts file.
-func saveAccountsToDisk(accounts *[]account) {
+// The file is replaced atomically and the previous generation is kept as accounts.bak
+// (see "writeFileWithBackup()") as well as within the backups directory (see "snapshotVault()").
+// If the file could not be written, the error is returned and the previous accounts file
+// is left untouched.
+func saveAccountsToDisk(accounts *[]account) error {
 	data, err := json.Marshal(*accounts)
-	checkError(err)
+	if err != nil {
+		return err
+	}
 
-	encryptedData := encrypt(data)
+	defer zero(&data)
 
-	WriteFileRel("accounts", *encryptedData)
+	if err = snapshotVault(); err != nil {
+		return err
+	}
+
+	encryptedData, err := encrypt(data)
+	if err != nil {
+		return err
+	}
+
+	err = writeFileWithBackup(vaultPath, *encryptedData)
 
 	zero(encryptedData)
-	zero(&data)
 
 	runtime.GC()
+
+	return err
 }
 
 // Checks whether the supplied error is nil. If not, this function panics the error.
@@ -80,146 +249,311 @@ func zero(slice *[]byte) {
 }
 
 // This function checks whether a password is "valid". A password is considered "valid"
-// if its SHA-1 hash is not included in the data returned by querying [HIBPs' password search by range]
-// api and there was no other error when querying (e.g. no internet connection, error in the hashing
-// function, etc).
+// if the configured "breachChecker" (by default querying [HIBPs' password search by range] api)
+// doesn't know the password.
 //
-// Two values will be returned:
+// Three values will be returned:
 //   - a boolean indicating whether the password is considered "valid"
 //   - a number indicating how many times the hash has appeared in the data set (refer to the
-//     afore mentioned [HIBPs' password search by range] docs for more information). Note that this
-//     number will be 0 if there was an error OR if the hash of the password just never appeared
-//     in a data set and is therefor considered "valid". Hence, if the returned boolean is false
-//     and the returned number == 0, an error must have occured.
+//     afore mentioned [HIBPs' password search by range] docs for more information)
+//   - an error if the password could not be checked (e.g. no internet connection or an unreadable
+//     offline database), which describes the configured source (see "breachCheckSettings.describe()")
 //
 // [HIBPs' password search by range]: https://haveibeenpwned.com/API/v3#SearchingPwnedPasswordsByRange
-func isPwValid(pw []byte) (bool, uint32) {
-	// deepcode ignore InsecureHash: The pwned passwords API only supports SHA-1 or NTLM hashes. I went with SHA-1. The actual hash is only used for querying the pwned passwords API, relativizing this issue.
-	h := sha1.New()
-	_, err := h.Write(pw)
-
-	defer h.Reset()
-
+func isPwValid(pw []byte) (bool, uint32, error) {
+	count, err := breachChecker.PwnedCount(pw)
 	if err != nil {
-		return false, 0
+		return false, 0, fmt.Errorf("Could not check the generated password against %s: %w", userSettings.BreachCheck.describe(), err)
 	}
 
-	finalHash := strings.TrimSpace(strings.ToUpper(hex.EncodeToString(h.Sum(nil))))
-	prefix, suffix := finalHash[:5], finalHash[5:]
+	return count == 0, count, nil
+}
 
-	resp, err := http.Get(fmt.Sprint("https://api.pwnedpasswords.com/range/", prefix))
+// Returns a random number within [0, n) using the cryptographically secure RNG implemented
+// in the [crypto/rand] package.
+func randomInt(n int) int {
+	bi, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	checkError(err)
+
+	return int(bi.Int64())
+}
 
-	if err != nil || resp.StatusCode != 200 {
-		return false, 0
+// Generates a password according to the given policy (see "pwPolicy") and returns the pointer
+// pointing to the password's byte slice. The length of the password is chosen randomly within the
+// range of the policy. First, the minimum number of characters of every class is chosen, the
+// remaining characters are chosen from all classes. Afterwards, the characters are shuffled.
+// Passwords containing repeated runs of characters are discarded. Passphrases are generated by
+// "generatePassphrase()" instead if the policy uses the passphrase mode. The policy has to be valid
+// (see "pwPolicy.validate()"). If no password without repeated runs has been generated within
+// "MAX_GENERATOR_ATTEMPTS" attempts, an error wrapping "ErrGeneratorExhausted" is returned.
+// The utilized RNG is the cryptographically secure RNG implemented in the [crypto/rand] package.
+//
+// Don't use this function directly as it will not check if the generated password
+// can be considered "valid". Use the "generatePw()" function instead.
+func generatePwInternal(policy pwPolicy) (*[]byte, error) {
+	if policy.Mode == PW_MODE_PASSPHRASE {
+		return generatePassphrase(policy), nil
 	}
 
-	defer resp.Body.Close()
+	classes, alphabet := policy.classes(), policy.alphabet()
 
-	data, err := io.ReadAll(resp.Body)
+	for range MAX_GENERATOR_ATTEMPTS {
+		length := policy.MinLength + randomInt(policy.MaxLength-policy.MinLength+1)
+		buf := make([]byte, 0, length)
 
-	if err != nil {
-		return false, 0
-	}
+		for _, c := range classes {
+			for range c.min {
+				buf = append(buf, c.chars[randomInt(len(c.chars))])
+			}
+		}
 
-	entries := strings.SplitSeq(string(data), "\r\n")
+		for len(buf) < length {
+			buf = append(buf, alphabet[randomInt(len(alphabet))])
+		}
 
-	for entry := range entries {
-		split := strings.Split(entry, ":")
-		pwdSuffix := split[0]
-		count, _ := (strconv.Atoi(split[1]))
+		for i := len(buf) - 1; i > 0; i-- {
+			j := randomInt(i + 1)
+			buf[i], buf[j] = buf[j], buf[i]
+		}
 
-		if pwdSuffix == suffix {
-			return false, uint32(count)
+		if !policy.hasRepeatedRun(buf) {
+			return &buf, nil
 		}
+
+		zero(&buf)
 	}
 
-	return true, 0
+	return &[]byte{}, fmt.Errorf("%w (no password without more than %d identical characters in a row within %d attempts, allow more repeats or more characters)",
+		ErrGeneratorExhausted, policy.MaxRepeat, MAX_GENERATOR_ATTEMPTS)
 }
 
-// Generates a password and returns the pointer pointing to the password's byte slice.
-// The utilized set of characters contains, in order:
-//   - all 26 letters of the English alphabet in lower case
-//   - all 26 letters of the English alphabet in upper case
-//   - all 10 digits (0-9)
-//   - the special characters ?, +, =, !, &, /, -, _, <, > and |
-//
-// Note that the digits appear thrice and the special characters appear twice in the
-// set of characters to increase their probability/rate of appearing in the resulting password.
-//
-// The resulting password will consist of at least 10 and at most 20 characters.
-// The utilized RNG is the cryptographically secure RNG implemented in the [crypto/rand] package.
+// Generates a password according to the given policy and returns the pointer pointing to its
+// byte slice using the "generatePwInternal()" function until the resulting bytes
+// are considered a valid password by the "isPwValid()" function. In case of an error,
+// a pointer to an empty byte slice is returned together with the error, which either wraps
+// "ErrGeneratorExhausted" or describes why the password could not be checked.
+func generatePw(policy pwPolicy) (*[]byte, error) {
+	for {
+		pwd, err := generatePwInternal(policy)
+		if err != nil {
+			return pwd, err
+		}
+
+		valid, _, err := isPwValid(*pwd)
+
+		if valid {
+			return pwd, nil
+		}
+
+		zero(pwd)
+
+		if err != nil {
+			return &[]byte{}, err
+		}
+	}
+}
+
+// Returns the path of the vault used if neither the -vault flag nor the PWDMAN_VAULT
+// environment variable is set. The vault is stored within the user's data directory
+// as specified by the [XDG Base Directory Specification] ($XDG_DATA_HOME/pwdman/accounts).
+// If $XDG_DATA_HOME is not set, ~/.local/share is used on Linux and %AppData% on Windows.
 //
-// Don't use this function directly as it will not check if the generated password
-// can be considered "valid". Use the "generatePw()" function instead.
-func generatePwInternal() *[]byte {
-	chars := []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ012345678901234567890123456789?+=!&/-_<>|?+=!&/-_<>|")
+// [XDG Base Directory Specification]: https://specifications.freedesktop.org/basedir-spec/latest/
+func defaultVaultPath() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+
+	if !filepath.IsAbs(dataHome) {
+		var err error
+
+		if runtime.GOOS == "windows" {
+			dataHome, err = os.UserConfigDir()
+		} else {
+			dataHome, err = os.UserHomeDir()
+			dataHome = filepath.Join(dataHome, ".local", "share")
+		}
 
-	bi, err := rand.Int(rand.Reader, big.NewInt(int64(21)))
-	checkError(err)
+		if err != nil {
+			return "", err
+		}
+	}
 
-	buf := make([]byte, bi.Int64()+10)
+	return filepath.Join(dataHome, "pwdman", "accounts"), nil
+}
 
-	for i := range buf {
-		bi, err = rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
-		checkError(err)
+// Returns the absolute path of the vault. The path is taken from the -vault flag (if not empty),
+// the PWDMAN_VAULT environment variable or "defaultVaultPath()", in that order.
+func resolveVaultPath(flagValue string) (string, error) {
+	path := flagValue
+
+	if len(path) == 0 {
+		path = os.Getenv("PWDMAN_VAULT")
+	}
 
-		buf[i] = chars[bi.Int64()]
+	if len(path) == 0 {
+		return defaultVaultPath()
 	}
 
-	return &buf
+	return filepath.Abs(path)
 }
 
-// Generates a password and returns the pointer pointing to its byte slice
-// using the "generatePwInternal()" function until the resulting bytes
-// are considered a valid password by the "isPwValid()" function. In case of an error,
-// a pointer to an empty byte slice is returned.
-func generatePw() *[]byte {
-	pwd := generatePwInternal()
-	b, n := isPwValid(*pwd)
+// Copies the vault of older versions of PwdMan (see "LEGACY_VAULT_PATH") and its .bak file to
+// the given default path, unless a vault already exists there. The times of the last modification
+// are kept, as they are used to migrate the accounts (see "migrateAccounts()"). The legacy vault
+// itself is left untouched. Returns the path of the legacy vault if it has been copied, or an empty
+// string if there is nothing to migrate. If a file could not be copied, the copies are removed again,
+// so that the migration is retried next time.
+func migrateLegacyVault(path string) (string, error) {
+	legacyPath, err := filepath.Abs(LEGACY_VAULT_PATH)
+	if err != nil || legacyPath == path {
+		return "", err
+	}
 
-	for !b && n != 0 {
-		zero(pwd)
-		pwd = generatePwInternal()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return "", nil
 	}
 
-	if !b && n == 0 {
-		return &[]byte{}
-	} else {
-		return pwd
+	if info, err := os.Stat(legacyPath); err != nil || !info.Mode().IsRegular() {
+		return "", nil
 	}
+
+	copied := []string{}
+
+	for _, suffix := range []string{"", ".bak"} {
+		err := copyFile(legacyPath+suffix, path+suffix)
+
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			for _, name := range copied {
+				os.Remove(name)
+			}
+
+			return "", fmt.Errorf("Could not copy %s to %s: %w", legacyPath+suffix, path+suffix, err)
+		}
+
+		copied = append(copied, path+suffix)
+	}
+
+	return legacyPath, nil
 }
 
-// Returns the string returned by [os.Getwd]. Or panics if the returned error != nil.
-func getWd() string {
-	wd, err := os.Getwd()
-	checkError(err)
+// Copies the given file atomically including the time of its last modification.
+func copyFile(src string, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
 
-	return wd
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(dst, data); err != nil {
+		return err
+	}
+
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
 }
 
-// Reads a file from an absolute path.
-func readFile(name string) []byte {
+// Reads a file from an absolute path. A file which doesn't exist is treated as an empty file.
+func readFile(name string) ([]byte, error) {
 	data, err := os.ReadFile(name)
 	if os.IsNotExist(err) {
-		return []byte{}
+		return []byte{}, nil
 	}
 
-	checkError(err)
+	return data, err
+}
+
+// Writes to a file using the in this file (global.go) defined FILE_MODE. The file and its
+// parent directories are created if necessary and the file is replaced atomically
+// (see "writeFileAtomic()"). If the file already exists, its previous content is preserved
+// in a file of the same name with the suffix ".bak" before it is replaced.
+// Also zeros the given data.
+func writeFileWithBackup(path string, data []byte) error {
+	defer zero(&data)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	previous, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if len(previous) > 0 {
+		err = writeFileAtomic(path+".bak", previous)
+		zero(&previous)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeFileAtomic(path, data)
+}
+
+// Writes data to a file without ever leaving a partially written file behind. The data is
+// written to a temporary file within the same directory first which is synced to disk and
+// then renamed over the original file. Hence, the file either contains the previous or the
+// new data, even if the process crashes, the disk is full or the power is lost mid-write.
+func writeFileAtomic(name string, data []byte) error {
+	tmp, err := stageFile(name, data)
+	if err != nil {
+		return err
+	}
+
+	// Does nothing if the temporary file has already been renamed
+	defer os.Remove(tmp)
 
-	return data
+	return replaceFile(tmp, name)
 }
 
-// Reads a file from a relative path (using the "getWd()" function).
-func readFileRel(name string) []byte {
-	return readFile(getWd() + string(os.PathSeparator) + name)
+// Writes data to a temporary file next to the given file and syncs it to disk. Returns the
+// path of the temporary file, which has to be renamed over the given file (see "replaceFile()")
+// or removed by the caller.
+func stageFile(name string, data []byte) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp*")
+	if err != nil {
+		return "", err
+	}
+
+	if err = tmp.Chmod(FILE_MODE); err == nil {
+		if _, err = tmp.Write(data); err == nil {
+			err = tmp.Sync()
+		}
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
 }
 
-// Writes to a file from a relative path (using the "getWd()" function) using the in this
-// file (global.go) defined FILE_MODE and the perm parameter.
-func WriteFileRel(name string, data []byte) error {
-	err := os.WriteFile(getWd()+string(os.PathSeparator)+name, data, FILE_MODE)
+// Renames the given temporary file (see "stageFile()") over the given file.
+func replaceFile(tmp string, name string) error {
+	if err := os.Rename(tmp, name); err != nil {
+		return err
+	}
 
-	zero(&data)
+	// Persist the rename itself. Directories can't be synced on every platform,
+	// hence errors are ignored.
+	if d, err := os.Open(filepath.Dir(name)); err == nil {
+		d.Sync()
+		d.Close()
+	}
 
-	return err
+	return nil
 }
@@ -10,6 +10,7 @@ require (
 	github.com/charmbracelet/bubbletea v1.3.5
 	github.com/charmbracelet/lipgloss v1.1.0
 	golang.design/x/clipboard v0.7.1
+	golang.org/x/crypto v0.39.0
 )
 
 require (
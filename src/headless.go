@@ -1,10 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"os"
 	"slices"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/help"
@@ -29,10 +31,75 @@ var (
 	pwAdditive       = actualPwAdditive
 	actualPwAdditive = baseStyle.Foreground(lipgloss.Color("127")).Render("Password copied!") + "\n"
 	savedAdditive    = baseStyle.Foreground(lipgloss.Color("127")).Render("Saved to disk!") + "\n"
+	errorStyle       = baseStyle.Foreground(lipgloss.Color("160"))
+	pathStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("240")).PaddingLeft(1)
 )
 
+// The error returned when initializing the clipboard. If not nil, passwords can't be copied.
+var clipboardErr error
+
+// Shows the given additive below the help for the given duration.
+func showAdditive(additive string, d time.Duration) {
+	pwAdditive = additive
+	pwCopied = true
+
+	time.AfterFunc(d, func() {
+		pwCopied = false
+	})
+}
+
+// Returns an additive describing the given error which occurred while saving the vault.
+func saveErrorAdditive(err error) string {
+	if errors.Is(err, ErrJournal) {
+		return errorStyle.Render(err.Error()) + "\n"
+	}
+
+	return errorStyle.Render("Could not save to disk: "+err.Error()) + "\n"
+}
+
+// The number of characters of an account ID shown in the accounts table.
+const SHORT_ID_LENGTH = 8
+
+// Returns the rows of the accounts table. The first (hidden) column contains the ID of the
+// account (see "selectedIndex()") and the second column its first characters. The accounts can
+// be filtered using the given search query (see "accountMatches()") and are sorted according to
+// the user settings (see "sortRows()"). The first account (the "New" entry) has no ID and is
+// never filtered.
+func accountRows(accounts []account, query string) []table.Row {
+	rows := []table.Row{}
+
+	for index, account := range accounts {
+		if index > 0 && !accountMatches(account, query) {
+			continue
+		}
+
+		rows = append(rows, table.Row{
+			account.ID,
+			account.ID[:min(len(account.ID), SHORT_ID_LENGTH)],
+			flaggedService(account),
+			account.Description,
+			account.Notes,
+		})
+	}
+
+	sortRows(rows, accounts, userSettings.Sort)
+
+	return rows
+}
+
+// Returns the index of the account selected in the accounts table (0 for the "New" entry).
+func (m model) selectedIndex() int {
+	row := m.table.SelectedRow()
+	if row == nil || len(row[0]) == 0 {
+		return 0
+	}
+
+	return max(accountIndex(*m.accounts, row[0]), 0)
+}
+
 type model struct {
 	table          table.Model
+	tableStyles    table.Styles
 	inpService     textinput.Model
 	inpDescription textinput.Model
 	inpNotes       textarea.Model
@@ -46,6 +113,64 @@ type model struct {
 	accounts  *[]account
 	selected  *account
 	focus     int
+
+	// The size of the terminal window
+	width  int
+	height int
+
+	// Whether the accounts of the active vault have been loaded
+	loaded bool
+
+	// Set if the accounts of the active vault could only be decoded partially. The session is
+	// read-only then, so that saving can never overwrite the undecodable accounts (see "readOnly()").
+	integrityErr error
+
+	// State of the vault picker (see "headless_vaults.go")
+	vaults vaultsView
+
+	// State of the master password change screen (see "headless_rekey.go")
+	rekeyStage int
+	prompt     pwPromptModel
+	newPw      []byte
+
+	// State of the backups screen (see "headless_backups.go")
+	backups backupsView
+
+	// State of the error screen (see "headless_errors.go")
+	failure errorView
+
+	// State of the password history screen (see "headless_history.go")
+	history historyView
+
+	// The changes which can be undone and redone (see "headless_undo.go")
+	undo []undoStep
+	redo []undoStep
+
+	// State of the journal screen (see "headless_journal.go")
+	journal journalView
+
+	// State of the trash screen (see "headless_trash.go")
+	trash trashView
+
+	// State of the audit screen (see "headless_audit.go")
+	audit auditView
+
+	// State of the reuse screen (see "headless_reuse.go")
+	reuse reuseView
+
+	// State of the password generator screen (see "headless_generator.go")
+	generator generatorView
+
+	// The generator policy of the selected account, nil if it uses the global policy (see "policyOf()")
+	policy *pwPolicy
+
+	// Whether the selected account is about to be moved to the trash (see "viewConfirmDelete()")
+	confirmDelete bool
+
+	// State of the search (see "headless_search.go"). The query stays applied after
+	// leaving the search mode until it is cleared.
+	search    textinput.Model
+	searching bool
 }
 
 type customKeyMap struct {
@@ -57,38 +182,181 @@ type customKeyMap struct {
 	CopyPw     key.Binding
 	GotoTop    key.Binding
 	GotoBottom key.Binding
+	Rekey      key.Binding
+	Backups    key.Binding
+	Vaults     key.Binding
+	Search     key.Binding
+	Sort       key.Binding
+	Undo       key.Binding
+	Redo       key.Binding
+	Journal    key.Binding
+	Trash      key.Binding
+	Audit      key.Binding
+	Reuse      key.Binding
 }
 
 type customSelKeyMap struct {
-	Blur   key.Binding
-	Next   key.Binding
-	Quit   key.Binding
-	Back   key.Binding
-	Save   key.Binding
-	Delete key.Binding
-	NewPw  key.Binding
-	ShowPw key.Binding
-	CopyPw key.Binding
+	Blur    key.Binding
+	Next    key.Binding
+	Quit    key.Binding
+	Back    key.Binding
+	Save    key.Binding
+	Delete  key.Binding
+	NewPw   key.Binding
+	ShowPw  key.Binding
+	CopyPw  key.Binding
+	Move    key.Binding
+	History key.Binding
 }
 
-func (m model) Init() tea.Cmd { return nil }
+func (m model) Init() tea.Cmd {
+	if m.vaults.active {
+		return textinput.Blink
+	}
+
+	return nil
+}
 
 // 𝕸𝖆𝖞 𝖙𝖍𝖊 𝖑𝖔𝖗𝖉'𝖘 𝖒𝖊𝖗𝖈𝖞 𝖇𝖊 𝖚𝖕𝖔𝖓 𝖙𝖍𝖊𝖊, 𝖋𝖔𝖗 𝖙𝖍𝖔𝖚 𝖑𝖆𝖞𝖊𝖘𝖙 𝖍𝖆𝖓𝖉 𝖚𝖕𝖔𝖓 𝖙𝖍𝖎𝖘 𝖜𝖔𝖊𝖋𝖚𝖑 𝖈𝖗𝖆𝖋𝖙, 𝖋𝖎𝖙 𝖋𝖔𝖗 𝖓𝖊𝖎𝖙𝖍𝖊𝖗 𝖒𝖆𝖓 𝖓𝖔𝖗 𝖇𝖊𝖆𝖘𝖙.
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
+	// The size of the window is tracked regardless of the active screen
+	if _, ok := msg.(tea.WindowSizeMsg); !ok {
+		if m.rekeyStage != REKEY_INACTIVE {
+			return m.updateRekey(msg)
+		}
+
+		if m.backups.active {
+			return m.updateBackups(msg)
+		}
+
+		if m.vaults.active {
+			return m.updateVaults(msg)
+		}
+
+		if m.failure.active {
+			return m.updateError(msg)
+		}
+
+		if m.history.active {
+			return m.updateHistory(msg)
+		}
+
+		if m.journal.active {
+			return m.updateJournal(msg)
+		}
+
+		if m.trash.active {
+			return m.updateTrash(msg)
+		}
+
+		if m.audit.active {
+			return m.updateAudit(msg)
+		}
+
+		if m.reuse.active {
+			return m.updateReuse(msg)
+		}
+
+		if m.generator.active {
+			return m.updateGenerator(msg)
+		}
+
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+	}
+
 	switch msg := msg.(type) {
 
 	case tea.KeyMsg:
+		// Any other key than the delete key cancels the deletion
+		if m.confirmDelete && !key.Matches(msg, m.SelKeyMap.Delete) {
+			m.confirmDelete = false
+			return m, nil
+		}
+
 		switch {
 
 		case key.Matches(msg, m.KeyMap.Quit):
 			return m, tea.Quit
 
+		case key.Matches(msg, m.KeyMap.Rekey):
+			if m.selected == nil && !m.readOnly() {
+				return m.startRekey()
+			}
+
+		case key.Matches(msg, m.KeyMap.Backups):
+			if m.selected == nil {
+				return m.startBackups()
+			}
+
+		case key.Matches(msg, m.KeyMap.Vaults):
+			if m.selected == nil {
+				return m.startVaults(VAULTS_OPEN)
+			}
+
+		case key.Matches(msg, m.KeyMap.Search):
+			if m.selected == nil {
+				return m.startSearch()
+			}
+
+		case key.Matches(msg, m.KeyMap.Undo):
+			if m.selected == nil {
+				return m.replayChange(true)
+			}
+
+		case key.Matches(msg, m.KeyMap.Redo):
+			if m.selected == nil {
+				return m.replayChange(false)
+			}
+
+		case key.Matches(msg, m.KeyMap.Journal):
+			if m.selected == nil {
+				return m.startJournal()
+			}
+
+		case key.Matches(msg, m.KeyMap.Trash):
+			if m.selected == nil {
+				return m.startTrash()
+			}
+
+		case key.Matches(msg, m.KeyMap.Audit):
+			if m.selected == nil {
+				return m.startAudit()
+			}
+
+		case key.Matches(msg, m.KeyMap.Reuse):
+			if m.selected == nil {
+				return m.startReuse()
+			}
+
+		case key.Matches(msg, m.KeyMap.Sort):
+			if m.selected == nil {
+				column := ""
+
+				if n := strings.Index("012345", msg.String()); n > 0 {
+					column = sortColumns[n-1]
+				}
+
+				m.sortBy(column)
+			}
+
+		case key.Matches(msg, m.SelKeyMap.History):
+			if m.selected != nil {
+				return m.startHistory()
+			}
+
+		case key.Matches(msg, m.SelKeyMap.Move):
+			if m.selected != nil && m.selectedIndex() != 0 && !m.readOnly() {
+				return m.startVaults(VAULTS_MOVE)
+			}
+
 		case key.Matches(msg, m.SelKeyMap.NewPw):
-			pwBuf := generatePw()
-			m.inpPw.SetValue(string(*pwBuf))
-			zero(pwBuf)
+			if m.selected != nil {
+				return m.startGenerator()
+			}
 
 		case key.Matches(msg, m.KeyMap.Blur), key.Matches(msg, m.SelKeyMap.Blur):
 			if blurred {
@@ -101,6 +369,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.KeyMap.LineUp.SetEnabled(true)
 				m.KeyMap.Quit.SetEnabled(true)
 				m.KeyMap.Select.SetEnabled(true)
+				m.KeyMap.Rekey.SetEnabled(rekeyable)
+				m.KeyMap.Backups.SetEnabled(true)
+				m.KeyMap.Vaults.SetEnabled(true)
+				m.KeyMap.Search.SetEnabled(true)
+				m.KeyMap.Sort.SetEnabled(true)
+				m.KeyMap.Undo.SetEnabled(true)
+				m.KeyMap.Redo.SetEnabled(true)
+				m.KeyMap.Journal.SetEnabled(true)
+				m.KeyMap.Trash.SetEnabled(true)
+				m.KeyMap.Audit.SetEnabled(true)
+				m.KeyMap.Reuse.SetEnabled(true)
 
 				m.SelKeyMap.Back.SetEnabled(true)
 				m.SelKeyMap.CopyPw.SetEnabled(true)
@@ -109,6 +388,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.SelKeyMap.Quit.SetEnabled(true)
 				m.SelKeyMap.Save.SetEnabled(true)
 				m.SelKeyMap.ShowPw.SetEnabled(true)
+				m.SelKeyMap.Move.SetEnabled(true)
+				m.SelKeyMap.History.SetEnabled(true)
 
 				m.table.Blur()
 				m.inpService.Blur()
@@ -130,6 +411,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.KeyMap.LineUp.SetEnabled(false)
 				m.KeyMap.Quit.SetEnabled(false)
 				m.KeyMap.Select.SetEnabled(false)
+				m.KeyMap.Rekey.SetEnabled(false)
+				m.KeyMap.Backups.SetEnabled(false)
+				m.KeyMap.Vaults.SetEnabled(false)
+				m.KeyMap.Search.SetEnabled(false)
+				m.KeyMap.Sort.SetEnabled(false)
+				m.KeyMap.Undo.SetEnabled(false)
+				m.KeyMap.Redo.SetEnabled(false)
+				m.KeyMap.Journal.SetEnabled(false)
+				m.KeyMap.Trash.SetEnabled(false)
+				m.KeyMap.Audit.SetEnabled(false)
+				m.KeyMap.Reuse.SetEnabled(false)
 
 				m.SelKeyMap.Back.SetEnabled(false)
 				m.SelKeyMap.CopyPw.SetEnabled(false)
@@ -138,6 +430,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.SelKeyMap.Quit.SetEnabled(false)
 				m.SelKeyMap.Save.SetEnabled(false)
 				m.SelKeyMap.ShowPw.SetEnabled(false)
+				m.SelKeyMap.Move.SetEnabled(false)
+				m.SelKeyMap.History.SetEnabled(false)
 
 				m.table.Blur()
 				m.inpService.Blur()
@@ -192,9 +486,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.table.Focus()
 
 		case key.Matches(msg, m.SelKeyMap.Delete):
-			// TODO: Ask for confirmation from the user like pwAdditive
-
-			index, _ := strconv.ParseInt(m.table.SelectedRow()[0], 10, 32)
+			index := m.selectedIndex()
 
 			if index == 0 {
 				m.inpService.Blur()
@@ -211,10 +503,32 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				break
 			}
 
-			*m.accounts = slices.Delete(*m.accounts, int(index), int(index)+1)
+			if m.readOnly() {
+				break
+			}
+
+			if !m.confirmDelete {
+				m.confirmDelete = true
+				break
+			}
+
+			m.confirmDelete = false
+
+			// The account is moved to the trash before it is removed from the vault, so that it is never lost
+			deleted := (*m.accounts)[index]
+
+			if err := trashAccount(deleted); err != nil {
+				showAdditive(errorStyle.Render("Could not move the entry to the trash: "+err.Error())+"\n", 10*time.Second)
+				break
+			}
+
+			*m.accounts = slices.Delete(*m.accounts, index, index+1)
+			m.recordUndo(&deleted, nil, index)
 
 			temp := (*m.accounts)[1:]
-			saveAccountsToDisk(&temp)
+			if err := saveAndJournal(&temp, newJournalEntry(&deleted, nil, "Moved to the trash")); err != nil {
+				showAdditive(saveErrorAdditive(err), 10*time.Second)
+			}
 
 			m.inpService.Blur()
 			m.inpDescription.Blur()
@@ -225,53 +539,66 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.focus = 0
 			m.selected = nil
 
-			rows := []table.Row{}
-
-			for index, account := range *m.accounts {
-				rows = append(rows, table.Row{
-					fmt.Sprint(index),
-					account.Service,
-					account.Description,
-					account.Notes,
-				})
-			}
-
-			m.table.SetRows(rows)
+			m.refreshRows()
 			m.table.Focus()
 
 		case key.Matches(msg, m.SelKeyMap.Save):
-			if m.selected == nil {
+			if m.selected == nil || m.readOnly() {
 				break
 			}
 
-			index, _ := strconv.ParseInt(m.table.SelectedRow()[0], 10, 32)
+			index := m.selectedIndex()
+			entries := []journalEntry{}
 
 			if index != 0 {
-				m.selected.Service = m.inpService.Value()
-				m.selected.Description = m.inpDescription.Value()
-				m.selected.Notes = m.inpNotes.Value()
-				m.selected.User = m.inpUser.Value()
-				m.selected.Pw = m.inpPw.Value()
+				edited := *m.selected
+				edited.Service = m.inpService.Value()
+				edited.Description = m.inpDescription.Value()
+				edited.Notes = m.inpNotes.Value()
+				edited.User = m.inpUser.Value()
+				edited.Pw = m.inpPw.Value()
+				edited.Policy = m.policy
+
+				if !sameContent(edited, *m.selected) {
+					recordPwChange(*m.selected, &edited)
+					edited.Modified = now()
+
+					previous := *m.selected
+					*m.selected = edited
+
+					m.recordUndo(&previous, &edited, index)
+					entries = append(entries, newJournalEntry(&previous, &edited, ""))
+				} else {
+					m.selected.Policy = m.policy
+				}
 			} else if len(m.inpService.Value()) > 0 && len(m.inpPw.Value()) > 0 {
-				*m.accounts = append(*m.accounts, account{
+				created := now()
+
+				added := account{
+					ID:          newAccountID(),
 					Service:     m.inpService.Value(),
 					Description: m.inpDescription.Value(),
 					Notes:       m.inpNotes.Value(),
 					User:        m.inpUser.Value(),
 					Pw:          m.inpPw.Value(),
-				})
+					Policy:      m.policy,
+					Created:     created,
+					Modified:    created,
+				}
+
+				*m.accounts = append(*m.accounts, added)
+
+				m.recordUndo(nil, &added, len(*m.accounts)-1)
+				entries = append(entries, newJournalEntry(nil, &added, ""))
 			}
 
 			// TODO: Add confirmation with help display like pwAdditive
 			temp := (*m.accounts)[1:]
-			saveAccountsToDisk(&temp)
-
-			pwAdditive = savedAdditive
-			pwCopied = true
-
-			time.AfterFunc(3*time.Second, func() {
-				pwCopied = false
-			})
+			if err := saveAndJournal(&temp, entries...); err != nil {
+				showAdditive(saveErrorAdditive(err), 10*time.Second)
+			} else {
+				showAdditive(savedAdditive, 3*time.Second)
+			}
 
 			m.inpService.Blur()
 			m.inpDescription.Blur()
@@ -282,23 +609,18 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.focus = 0
 			m.selected = nil
 
-			rows := []table.Row{}
+			m.refreshRows()
+			m.table.Focus()
 
-			for index, account := range *m.accounts {
-				rows = append(rows, table.Row{
-					fmt.Sprint(index),
-					account.Service,
-					account.Description,
-					account.Notes,
-				})
+		case key.Matches(msg, m.KeyMap.CopyPw), key.Matches(msg, m.SelKeyMap.CopyPw):
+			if clipboardErr != nil {
+				showAdditive(errorStyle.Render("The clipboard is unavailable: "+clipboardErr.Error())+"\n", 10*time.Second)
+				break
 			}
 
-			m.table.SetRows(rows)
-			m.table.Focus()
+			index := m.selectedIndex()
 
-		case key.Matches(msg, m.KeyMap.CopyPw), key.Matches(msg, m.SelKeyMap.CopyPw):
 			if m.selected == nil {
-				index, _ := strconv.ParseInt(m.table.SelectedRow()[0], 10, 32)
 				account := (*m.accounts)[index]
 
 				clipboard.Write(clipboard.FmtText, []byte(account.Pw))
@@ -306,17 +628,27 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				clipboard.Write(clipboard.FmtText, []byte(m.selected.Pw))
 			}
 
-			pwAdditive = actualPwAdditive
-			pwCopied = true
+			showAdditive(actualPwAdditive, 3*time.Second)
 
-			time.AfterFunc(3*time.Second, func() {
-				pwCopied = false
-			})
+			// Remembers when the password has been used (see "SORT_LAST_USED"), unless the vault is read-only
+			if index != 0 && m.integrityErr == nil {
+				(*m.accounts)[index].LastUsed = now()
+
+				temp := (*m.accounts)[1:]
+				if err := saveUsageToDisk(&temp); err != nil {
+					showAdditive(saveErrorAdditive(err), 10*time.Second)
+				}
+
+				if userSettings.Sort.Column == SORT_LAST_USED {
+					m.refreshRows()
+				}
+			}
 
 		case key.Matches(msg, m.KeyMap.Select), key.Matches(msg, m.SelKeyMap.Next), key.Matches(msg, m.SelKeyMap.Back):
 			if m.selected == nil {
-				index, _ := strconv.ParseInt(m.table.SelectedRow()[0], 10, 32)
+				index := m.selectedIndex()
 				m.selected = &(*m.accounts)[index]
+				m.policy = m.selected.Policy
 
 				if index != 0 {
 					account := *m.selected
@@ -379,13 +711,24 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		workableWidth := msg.Width
 		workableHeight := msg.Height
 
+		m.width = workableWidth
+		m.height = workableHeight
+		m.backups.resize(workableWidth, workableHeight)
+		m.history.resize(workableWidth, workableHeight)
+		m.journal.resize(workableWidth, workableHeight)
+		m.trash.resize(workableWidth, workableHeight)
+		m.audit.resize(workableWidth, workableHeight)
+		m.reuse.resize(workableWidth, workableHeight)
+		m.vaults.resize(workableWidth, workableHeight)
+
 		extraSpace := 13
 
 		m.table.SetColumns([]table.Column{
-			{Title: "ID", Width: 3},
+			{Title: "", Width: 0},
+			{Title: "ID", Width: SHORT_ID_LENGTH},
 			{Title: "Service", Width: workableWidth / 5},
 			{Title: "Description", Width: workableWidth / 5 * 2},
-			{Title: "Notes", Width: workableWidth/5*2 - extraSpace},
+			{Title: "Notes", Width: workableWidth/5*2 - extraSpace - SHORT_ID_LENGTH + 3},
 		})
 
 		m.table.SetHeight(workableHeight / 3 * 2)
@@ -426,8 +769,56 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) View() string {
+	if m.rekeyStage != REKEY_INACTIVE {
+		return m.prompt.View()
+	}
+
+	if m.backups.active {
+		return m.viewBackups()
+	}
+
+	if m.vaults.active {
+		return m.viewVaults()
+	}
+
+	if m.failure.active {
+		return m.viewError()
+	}
+
+	if m.history.active {
+		return m.viewHistory()
+	}
+
+	if m.journal.active {
+		return m.viewJournal()
+	}
+
+	if m.trash.active {
+		return m.viewTrash()
+	}
+
+	if m.audit.active {
+		return m.viewAudit()
+	}
+
+	if m.reuse.active {
+		return m.viewReuse()
+	}
+
+	if m.generator.active {
+		return m.viewGenerator()
+	}
+
 	if m.selected == nil {
-		finalRender := baseStyle.Render(m.table.View()) + "\n"
+		finalRender := pathStyle.Render("Vault: "+vaultPath) + viewSort() + "\n"
+
+		if m.integrityErr != nil {
+			finalRender += errorStyle.Render(readOnlyNotice(m.integrityErr)) + "\n"
+		}
+
+		finalRender += m.viewSearch()
+		finalRender += baseStyle.Render(m.table.View()) + "\n"
+		finalRender += m.viewConfirmDelete()
 
 		if blurred {
 			finalRender += baseStyle.Render(m.Help.ShortHelpView(m.KeyMap.ShortHelp())) + "\n"
@@ -443,12 +834,13 @@ func (m model) View() string {
 	}
 
 	render := fmt.Sprintf(
-		"Service\n%s\n\nDescription\n%s\n\nNotes\n%s\n\nUser\n%s\n\nPassword\n%s\n",
+		"Service\n%s\n\nDescription\n%s\n\nNotes\n%s\n\nUser\n%s\n\nPassword\n%s\n%s",
 		m.inpService.View(),
 		m.inpDescription.View(),
 		m.inpNotes.View(),
 		m.inpUser.View(),
 		m.inpPw.View(),
+		m.viewStrength(),
 	)
 
 	var help string
@@ -465,6 +857,8 @@ func (m model) View() string {
 		baseStyle.Render(help),
 	)
 
+	finalRender += m.viewConfirmDelete()
+
 	if pwCopied {
 		finalRender += pwAdditive
 	}
@@ -481,7 +875,8 @@ func (km customKeyMap) FullHelp() [][]key.Binding {
 	km.Blur.SetHelp("esc", "Lock focus")
 	return [][]key.Binding{
 		{km.Blur, km.Select, km.CopyPw, km.LineUp, km.LineDown},
-		{km.GotoTop, km.GotoBottom, km.Quit},
+		{km.GotoTop, km.GotoBottom, km.Search, km.Sort, km.Undo, km.Redo},
+		{km.Vaults, km.Backups, km.Journal, km.Trash, km.Audit, km.Reuse, km.Rekey, km.Quit},
 	}
 }
 
@@ -493,39 +888,214 @@ func (km customSelKeyMap) ShortHelp() []key.Binding {
 func (km customSelKeyMap) FullHelp() [][]key.Binding {
 	km.Blur.SetHelp("esc", "Lock focus")
 	return [][]key.Binding{
-		{km.Blur, km.Next, km.NewPw, km.ShowPw, km.CopyPw},
-		{km.Save, km.Delete, km.Back, km.Quit},
+		{km.Blur, km.Next, km.NewPw, km.ShowPw, km.CopyPw, km.History},
+		{km.Save, km.Delete, km.Move, km.Back, km.Quit},
+	}
+}
+
+// A small model used to ask the user for a password before or during the main TUI.
+// If the prompt is run as its own program, it quits once the password has been submitted
+// or the prompt has been cancelled. Otherwise, the embedding model has to check "submitted"
+// and "cancelled" after every update.
+type pwPromptModel struct {
+	input      textinput.Model
+	help       help.Model
+	title      string
+	hint       string
+	standalone bool
+	submitted  bool
+	cancelled  bool
+}
+
+// Returns a new password prompt displaying the given title and hint.
+func newPwPrompt(title string, hint string) pwPromptModel {
+	ti := textinput.New()
+	ti.Placeholder = "Master password"
+	ti.Width = 40
+	ti.EchoMode = textinput.EchoPassword
+	ti.EchoCharacter = '•'
+	ti.PromptStyle = focusedStyle
+	ti.Focus()
+
+	return pwPromptModel{input: ti, help: help.New(), title: title, hint: hint}
+}
+
+// The key bindings of "pwPromptModel".
+var (
+	promptSubmit = key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "Confirm"),
+	)
+	promptCancel = key.NewBinding(
+		key.WithKeys("esc", "ctrl+c"),
+		key.WithHelp("esc/ctrl+c", "Cancel"),
+	)
+)
+
+func (p pwPromptModel) Init() tea.Cmd { return textinput.Blink }
+
+func (p pwPromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+
+		case key.Matches(msg, promptCancel):
+			p.cancelled = true
+
+			if p.standalone {
+				return p, tea.Quit
+			}
+
+			return p, nil
+
+		case key.Matches(msg, promptSubmit):
+			if len(p.input.Value()) == 0 {
+				p.hint = "The password must not be empty!"
+				return p, nil
+			}
+
+			p.submitted = true
+
+			if p.standalone {
+				return p, tea.Quit
+			}
+
+			return p, nil
+		}
 	}
+
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+
+	return p, cmd
 }
 
-func setupHeadless() {
+func (p pwPromptModel) View() string {
+	finalRender := baseStyle.Render(p.title+"\n\n"+p.input.View()) + "\n"
+	finalRender += baseStyle.Render(p.help.ShortHelpView([]key.Binding{promptSubmit, promptCancel})) + "\n"
+
+	if len(p.hint) > 0 {
+		finalRender += baseStyle.Foreground(lipgloss.Color("127")).Render(p.hint) + "\n"
+	}
+
+	return finalRender
+}
+
+// Runs a password prompt with the given title and hint. Returns the entered password
+// and whether the user submitted it (false if the user cancelled the prompt).
+func promptPw(title string, hint string) ([]byte, bool) {
+	return runPwPrompt(newPwPrompt(title, hint))
+}
+
+// Runs the given password prompt as its own program. The prompt is rendered to stderr,
+// so that it is visible even if the output of PwdMan is piped (see "runCommand()").
+func runPwPrompt(p pwPromptModel) ([]byte, bool) {
+	p.standalone = true
+
+	res, err := tea.NewProgram(p, tea.WithOutput(os.Stderr)).Run()
+	if err != nil {
+		log.Fatalf("Error running program: %v", err)
+	}
+
+	p = res.(pwPromptModel)
+
+	return []byte(p.input.Value()), p.submitted
+}
+
+// Asks the user to choose a new master password which has to be entered twice.
+// Returns the new password and false if the user cancelled.
+func chooseMasterPw(title string) ([]byte, bool) {
+	hint := ""
+
+	for {
+		pw, ok := promptPw(title, hint)
+		if !ok {
+			return nil, false
+		}
+
+		confirmation, ok := promptPw("Confirm your master password", "")
+		if !ok {
+			zero(&pw)
+			return nil, false
+		}
+
+		if slices.Equal(pw, confirmation) {
+			zero(&confirmation)
+			return pw, true
+		}
+
+		zero(&pw)
+		zero(&confirmation)
+
+		hint = "The passwords did not match!"
+	}
+}
+
+// Asks the user for the master password and stores it in "masterPw" if the vault requires one.
+// If no accounts file exists yet, the user has to enter the new master password twice.
+// Returns false if the user cancelled or the vault could not be read.
+func unlockVault() (bool, error) {
+	encryptedData, err := readFile(vaultPath)
+	if err != nil {
+		return false, err
+	}
+
+	exists, legacy := len(encryptedData) > 0, !isFramedVault(encryptedData)
+	required := needsMasterPw(encryptedData)
+	zero(&encryptedData)
+
+	// Legacy vaults are protected by the machine ID or the DPAPI instead of a master password
+	// (see "nativeDecrypt()"), they are upgraded once a master password has been chosen
+	if !required || (exists && legacy) {
+		return true, nil
+	}
+
+	var ok bool
+
+	if exists {
+		masterPw, ok = promptPw("Enter your master password", "")
+	} else {
+		masterPw, ok = chooseMasterPw("Choose a master password for your new vault")
+	}
+
+	return ok, nil
+}
+
+// Starts the TUI. The vault picker is shown first unless openDirectly is true,
+// in which case the active vault is opened right away.
+func setupHeadless(openDirectly bool) {
+	m, _ := newModel(&[]account{}).startVaults(VAULTS_OPEN)
+
+	var res tea.Model = m
+
+	if openDirectly {
+		res, _ = m.openVault(vaultPath)
+	}
+
+	if _, err := tea.NewProgram(res, tea.WithAltScreen()).Run(); err != nil {
+		log.Fatalf("Error running program: %v", err)
+	}
+}
+
+// Returns the model of the TUI showing the given accounts.
+func newModel(accounts *[]account) model {
 	accountsPtr := &[]account{
 		{Service: "New", Description: "New entry", Notes: "", User: "", Pw: ""},
 	}
 
-	*accountsPtr = append(*accountsPtr, *getAllAccounts()...)
+	*accountsPtr = append(*accountsPtr, *accounts...)
 
 	columns := []table.Column{
-		{Title: "ID", Width: 3},
+		// Holds the ID of the account and is not rendered (see "accountRows()")
+		{Title: "", Width: 0},
+		{Title: "ID", Width: SHORT_ID_LENGTH},
 		{Title: "Service", Width: 10},
 		{Title: "Description", Width: 20},
 		{Title: "Notes", Width: 10},
 	}
 
-	rows := []table.Row{}
-
-	for index, account := range *accountsPtr {
-		rows = append(rows, table.Row{
-			fmt.Sprint(index),
-			account.Service,
-			account.Description,
-			account.Notes,
-		})
-	}
-
 	t := table.New(
 		table.WithColumns(columns),
-		table.WithRows(rows),
+		table.WithRows(accountRows(*accountsPtr, "")),
 		table.WithFocused(true),
 		table.WithHeight(7),
 	)
@@ -575,8 +1145,54 @@ func setupHeadless() {
 			key.WithKeys("end"),
 			key.WithHelp("end", "Go to end"),
 		),
+		Rekey: key.NewBinding(
+			key.WithKeys("ctrl+k"),
+			key.WithHelp("ctrl+k", "Change master password"),
+		),
+		Backups: key.NewBinding(
+			key.WithKeys("ctrl+b"),
+			key.WithHelp("ctrl+b", "Backups"),
+		),
+		Vaults: key.NewBinding(
+			key.WithKeys("ctrl+o"),
+			key.WithHelp("ctrl+o", "Switch vault"),
+		),
+		Search: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "Search"),
+		),
+		Sort: key.NewBinding(
+			key.WithKeys("0", "1", "2", "3", "4", "5"),
+			key.WithHelp("1-5/0", "Sort by column/reset"),
+		),
+		Undo: key.NewBinding(
+			key.WithKeys("ctrl+z"),
+			key.WithHelp("ctrl+z", "Undo"),
+		),
+		Redo: key.NewBinding(
+			key.WithKeys("ctrl+y"),
+			key.WithHelp("ctrl+y", "Redo"),
+		),
+		Journal: key.NewBinding(
+			key.WithKeys("ctrl+j"),
+			key.WithHelp("ctrl+j", "Journal"),
+		),
+		Trash: key.NewBinding(
+			key.WithKeys("ctrl+t"),
+			key.WithHelp("ctrl+t", "Trash"),
+		),
+		Audit: key.NewBinding(
+			key.WithKeys("ctrl+a"),
+			key.WithHelp("ctrl+a", "Breach check"),
+		),
+		Reuse: key.NewBinding(
+			key.WithKeys("ctrl+u"),
+			key.WithHelp("ctrl+u", "Password reuse"),
+		),
 	}
 
+	km.Rekey.SetEnabled(rekeyable)
+
 	selKm := customSelKeyMap{
 		Blur:   km.Blur,
 		Next:   km.Select,
@@ -602,6 +1218,14 @@ func setupHeadless() {
 			key.WithKeys("ctrl+d"),
 			key.WithHelp("ctrl+d", "Delete this entry"),
 		),
+		Move: key.NewBinding(
+			key.WithKeys("ctrl+x"),
+			key.WithHelp("ctrl+x", "Move to another vault"),
+		),
+		History: key.NewBinding(
+			key.WithKeys("ctrl+l"),
+			key.WithHelp("ctrl+l", "Password history"),
+		),
 	}
 
 	tiServ := textinput.New()
@@ -622,8 +1246,21 @@ func setupHeadless() {
 	tiPw.EchoMode = textinput.EchoPassword
 	tiPw.EchoCharacter = '•'
 
-	m := model{t, tiServ, tiDesc, taNotes, tiUser, tiPw, km, selKm, help.New(), help.New(), accountsPtr, nil, 0}
-	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
-		log.Fatalf("Error running program: %v", err)
+	m := model{
+		table:          t,
+		tableStyles:    s,
+		inpService:     tiServ,
+		inpDescription: tiDesc,
+		inpNotes:       taNotes,
+		inpUser:        tiUser,
+		inpPw:          tiPw,
+		KeyMap:         km,
+		SelKeyMap:      selKm,
+		Help:           help.New(),
+		SelHelp:        help.New(),
+		accounts:       accountsPtr,
+		search:         newSearchInput(),
 	}
+
+	return m
 }
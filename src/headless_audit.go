@@ -0,0 +1,270 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The state of the audit screen which checks the passwords of all accounts of the active vault
+// against Have I Been Pwned (see "audit.go"). The passwords are checked one after another in
+// the background, so that the TUI stays responsive.
+type auditView struct {
+	active bool
+	table  table.Model
+	// The accounts which are yet to be checked, the first one is being checked right now
+	pending []account
+	// The number of accounts checked by the current run
+	total  int
+	err    error
+	KeyMap auditKeyMap
+}
+
+type auditKeyMap struct {
+	Refresh key.Binding
+	Back    key.Binding
+	Quit    key.Binding
+}
+
+// Sent once the password of an account has been checked (see "checkAccount()").
+type auditCheckedMsg struct {
+	id     string
+	result auditResult
+	err    error
+}
+
+// Returns the command checking the password of the given account.
+func checkAccount(a account) tea.Cmd {
+	return func() tea.Msg {
+		r, err := auditAccount(a)
+		return auditCheckedMsg{id: a.ID, result: r, err: err}
+	}
+}
+
+// Returns the columns of the audit table for the given width of the terminal window.
+func auditColumns(width int) []table.Column {
+	return []table.Column{
+		{Title: "Service", Width: max(width/5, 12)},
+		{Title: "User", Width: max(width/5, 12)},
+		{Title: "Breaches", Width: max(width/5, 24)},
+		{Title: "Checked at", Width: 20},
+	}
+}
+
+// Adapts the size of the audit table to the size of the terminal window.
+func (av *auditView) resize(width int, height int) {
+	av.table.SetColumns(auditColumns(width))
+	av.table.SetHeight(height / 3 * 2)
+}
+
+// Fills the audit table with the given accounts. Compromised accounts are listed first,
+// the most commonly breached password at the top.
+func (av *auditView) reload(accounts []account) {
+	accounts = slices.Clone(accounts)
+
+	slices.SortStableFunc(accounts, func(a, b account) int {
+		countA, _ := breachCount(a)
+		countB, _ := breachCount(b)
+
+		return cmp.Compare(countB, countA)
+	})
+
+	rows := []table.Row{}
+
+	for _, a := range accounts {
+		status, checked := "Not checked", ""
+
+		switch count, ok := breachCount(a); {
+
+		case len(a.Pw) == 0:
+			status = "No password"
+
+		case len(av.pending) > 0 && av.pending[0].ID == a.ID:
+			status = "Checking..."
+
+		case slices.ContainsFunc(av.pending, func(p account) bool { return p.ID == a.ID }):
+			status = "Pending"
+
+		case ok:
+			status = formatBreaches(count)
+			checked = auditResults[a.ID].Checked.Local().Format(time.DateTime)
+
+			if count > 0 {
+				status = "⚠ " + status
+			}
+		}
+
+		rows = append(rows, table.Row{a.Service, a.User, status, checked})
+	}
+
+	av.table.SetRows(rows)
+}
+
+// Opens the audit screen and checks the passwords which haven't been checked yet or whose
+// results are stale (see "pendingAudits()").
+func (m model) startAudit() (tea.Model, tea.Cmd) {
+	t := table.New(
+		table.WithColumns(auditColumns(m.width)),
+		table.WithFocused(true),
+	)
+	t.SetStyles(m.tableStyles)
+
+	m.audit = auditView{
+		active: true,
+		table:  t,
+		KeyMap: auditKeyMap{
+			Refresh: key.NewBinding(
+				key.WithKeys("ctrl+r"),
+				key.WithHelp("ctrl+r", "Check all passwords again"),
+			),
+			Back: key.NewBinding(
+				key.WithKeys("esc", "shift+tab"),
+				key.WithHelp("esc/shift+tab", "Back"),
+			),
+			Quit: key.NewBinding(
+				key.WithKeys("ctrl+c"),
+				key.WithHelp("ctrl+c", "Quit"),
+			),
+		},
+	}
+	m.audit.resize(m.width, m.height)
+	m.table.Blur()
+
+	return m, tea.Batch(tea.ClearScreen, m.runAudit(false))
+}
+
+// Starts checking the pending passwords of the active vault. If refresh is true, all passwords
+// are checked again.
+func (m *model) runAudit(refresh bool) tea.Cmd {
+	accounts := (*m.accounts)[1:]
+
+	m.audit.pending = pendingAudits(accounts, refresh)
+	m.audit.err = nil
+
+	if userSettings.BreachCheck.Source == BREACH_SOURCE_NONE {
+		m.audit.pending = nil
+		m.audit.err = ErrBreachCheckDisabled
+	}
+
+	m.audit.total = len(m.audit.pending)
+	m.audit.reload(accounts)
+
+	if len(m.audit.pending) == 0 {
+		return nil
+	}
+
+	return checkAccount(m.audit.pending[0])
+}
+
+// Handles messages while the audit screen is active.
+func (m model) updateAudit(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+
+	case auditCheckedMsg:
+		if len(m.audit.pending) == 0 || m.audit.pending[0].ID != msg.id {
+			return m, nil
+		}
+
+		accounts := (*m.accounts)[1:]
+
+		// Without a connection, the remaining passwords can't be checked either
+		if msg.err != nil {
+			m.audit.pending = nil
+			m.audit.err = fmt.Errorf("Could not check the passwords against Have I Been Pwned: %w", msg.err)
+			m.audit.reload(accounts)
+
+			return m, nil
+		}
+
+		auditResults[msg.id] = msg.result
+		m.audit.pending = m.audit.pending[1:]
+
+		if err := saveAuditCache(accounts); err != nil {
+			m.audit.err = fmt.Errorf("Could not save the results: %w", err)
+		}
+
+		m.audit.reload(accounts)
+		m.refreshRows()
+
+		if len(m.audit.pending) > 0 {
+			return m, checkAccount(m.audit.pending[0])
+		}
+
+		return m, nil
+
+	case tea.KeyMsg:
+		switch {
+
+		case key.Matches(msg, m.audit.KeyMap.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, m.audit.KeyMap.Back):
+			m.audit = auditView{}
+			m.table.Focus()
+
+			return m, tea.ClearScreen
+
+		case key.Matches(msg, m.audit.KeyMap.Refresh):
+			if len(m.audit.pending) == 0 {
+				return m, m.runAudit(true)
+			}
+
+			return m, nil
+		}
+	}
+
+	m.audit.table, cmd = m.audit.table.Update(msg)
+
+	return m, cmd
+}
+
+func (m model) viewAudit() string {
+	accounts := (*m.accounts)[1:]
+	content := "Breach check (Have I Been Pwned)\n\n"
+
+	if len(accounts) == 0 {
+		content += "The vault is empty."
+	} else {
+		content += m.audit.table.View()
+	}
+
+	finalRender := baseStyle.Render(content) + "\n"
+	finalRender += baseStyle.Render(m.Help.ShortHelpView([]key.Binding{
+		m.audit.KeyMap.Refresh, m.audit.KeyMap.Back, m.audit.KeyMap.Quit,
+	})) + "\n"
+
+	if pending := len(m.audit.pending); pending > 0 {
+		finalRender += pathStyle.Render(fmt.Sprintf("Checking %d of %d passwords...", m.audit.total-pending+1, m.audit.total)) + "\n"
+	} else if m.audit.err == nil {
+		compromised := 0
+
+		for _, a := range accounts {
+			if count, ok := breachCount(a); ok && count > 0 {
+				compromised++
+			}
+		}
+
+		if compromised > 0 {
+			finalRender += errorStyle.Render(fmt.Sprintf("%d of %d passwords have appeared in data breaches, change them as soon as possible.", compromised, len(accounts))) + "\n"
+		} else {
+			finalRender += pathStyle.Render("None of the checked passwords have appeared in a data breach.") + "\n"
+		}
+	}
+
+	if m.audit.err != nil {
+		finalRender += errorStyle.Render(m.audit.err.Error()) + "\n"
+	}
+
+	if pwCopied {
+		finalRender += pwAdditive
+	}
+
+	return finalRender
+}
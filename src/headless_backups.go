@@ -0,0 +1,272 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	addedStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("34"))
+	removedStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("160"))
+	changedStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("178"))
+	restoredAdditive = baseStyle.Foreground(lipgloss.Color("127")).Render("Snapshot restored!") + "\n"
+)
+
+// The state of the backups screen which lists the snapshots of the accounts file
+// and allows to restore one of them after showing the differences to the current vault.
+type backupsView struct {
+	active    bool
+	table     table.Model
+	snapshots []snapshot
+	preview   *snapshot
+	accounts  []account
+	diff      accountDiff
+	err       error
+	KeyMap    backupsKeyMap
+}
+
+type backupsKeyMap struct {
+	Open    key.Binding
+	Restore key.Binding
+	Back    key.Binding
+	Quit    key.Binding
+}
+
+// Returns the key bindings shown in the help of the backups screen.
+func (bv backupsView) helpBindings() []key.Binding {
+	if bv.preview == nil {
+		return []key.Binding{bv.KeyMap.Open, bv.KeyMap.Back, bv.KeyMap.Quit}
+	}
+
+	return []key.Binding{bv.KeyMap.Restore, bv.KeyMap.Back, bv.KeyMap.Quit}
+}
+
+// Returns the columns of the snapshots table for the given width of the terminal window.
+func backupsColumns(width int) []table.Column {
+	return []table.Column{
+		{Title: "Saved at", Width: max(width/3, 20)},
+		{Title: "Size", Width: max(width/5, 10)},
+	}
+}
+
+// Adapts the size of the snapshots table to the size of the terminal window.
+func (bv *backupsView) resize(width int, height int) {
+	bv.table.SetColumns(backupsColumns(width))
+	bv.table.SetHeight(height / 3 * 2)
+}
+
+// Opens the backups screen.
+func (m model) startBackups() (tea.Model, tea.Cmd) {
+	snapshots, err := listSnapshots()
+	if err != nil {
+		showAdditive(errorStyle.Render("Could not list the backups: "+err.Error())+"\n", 10*time.Second)
+		return m, nil
+	}
+
+	rows := []table.Row{}
+
+	for _, s := range snapshots {
+		rows = append(rows, table.Row{
+			s.Time.Local().Format(time.DateTime),
+			fmt.Sprintf("%d bytes", s.Size),
+		})
+	}
+
+	t := table.New(
+		table.WithColumns(backupsColumns(m.width)),
+		table.WithRows(rows),
+		table.WithFocused(true),
+	)
+	t.SetStyles(m.tableStyles)
+
+	m.backups = backupsView{
+		active:    true,
+		table:     t,
+		snapshots: snapshots,
+		KeyMap: backupsKeyMap{
+			Open: key.NewBinding(
+				key.WithKeys("enter", "tab"),
+				key.WithHelp("enter/tab", "Compare with current vault"),
+			),
+			Restore: key.NewBinding(
+				key.WithKeys("ctrl+s"),
+				key.WithHelp("ctrl+s", "Restore this snapshot"),
+			),
+			Back: key.NewBinding(
+				key.WithKeys("esc", "shift+tab"),
+				key.WithHelp("esc/shift+tab", "Back"),
+			),
+			Quit: key.NewBinding(
+				key.WithKeys("ctrl+c"),
+				key.WithHelp("ctrl+c", "Quit"),
+			),
+		},
+	}
+	m.backups.resize(m.width, m.height)
+	m.table.Blur()
+
+	return m, tea.ClearScreen
+}
+
+// Handles messages while the backups screen is active.
+func (m model) updateBackups(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+
+		case key.Matches(msg, m.backups.KeyMap.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, m.backups.KeyMap.Back):
+			if m.backups.preview != nil {
+				m.backups.preview = nil
+				m.backups.accounts = nil
+				m.backups.err = nil
+
+				return m, tea.ClearScreen
+			}
+
+			m.backups = backupsView{}
+
+			// Returns to the error screen if the backups screen has been opened from there
+			if !m.failure.active {
+				m.table.Focus()
+			}
+
+			return m, tea.ClearScreen
+
+		case key.Matches(msg, m.backups.KeyMap.Open):
+			if m.backups.preview != nil || len(m.backups.snapshots) == 0 {
+				break
+			}
+
+			s := m.backups.snapshots[m.backups.table.Cursor()]
+
+			accounts, err := loadSnapshot(s)
+			if err != nil {
+				m.backups.err = err
+				break
+			}
+
+			m.backups.err = nil
+			m.backups.preview = &s
+			m.backups.accounts = accounts
+			m.backups.diff = diffAccounts((*m.accounts)[1:], accounts)
+
+			return m, tea.ClearScreen
+
+		case key.Matches(msg, m.backups.KeyMap.Restore):
+			if m.backups.preview == nil {
+				break
+			}
+
+			note := "Restored the snapshot saved at " + m.backups.preview.Time.Local().Format(time.DateTime)
+			entries := journalDiff((*m.accounts)[1:], m.backups.accounts, note)
+
+			err := saveAndJournal(&m.backups.accounts, entries...)
+			if err != nil && !errors.Is(err, ErrJournal) {
+				m.backups.err = err
+				break
+			}
+
+			*m.accounts = append((*m.accounts)[:1], m.backups.accounts...)
+
+			m.refreshRows()
+			m.table.GotoTop()
+			m.table.Focus()
+
+			m.backups = backupsView{}
+			m.failure = errorView{}
+			m.integrityErr = nil
+			m.loaded = true
+			m.clearUndo()
+
+			if err != nil {
+				showAdditive(saveErrorAdditive(err), 10*time.Second)
+			} else {
+				showAdditive(restoredAdditive, 3*time.Second)
+			}
+
+			return m, tea.ClearScreen
+		}
+	}
+
+	if m.backups.preview == nil {
+		m.backups.table, cmd = m.backups.table.Update(msg)
+	}
+
+	return m, cmd
+}
+
+// Renders a list of accounts prefixed with the given sign using the given style.
+func renderDiffSection(title string, sign string, style lipgloss.Style, accounts []account) string {
+	if len(accounts) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(style.Render(fmt.Sprintf("%s (%d)", title, len(accounts))) + "\n")
+
+	for _, a := range accounts {
+		line := a.Service
+
+		if len(a.User) > 0 {
+			line += " (" + a.User + ")"
+		}
+
+		sb.WriteString(style.Render("  "+sign+" "+line) + "\n")
+	}
+
+	return sb.String() + "\n"
+}
+
+func (m model) viewBackups() string {
+	var content string
+
+	if m.backups.preview == nil {
+		if len(m.backups.snapshots) == 0 {
+			content = "There are no backups yet. A snapshot is created every time the vault is saved."
+		} else {
+			content = "Backups\n\n" + m.backups.table.View()
+		}
+	} else {
+		diff := m.backups.diff
+
+		content = fmt.Sprintf(
+			"Restoring the snapshot saved at %s would change the current vault as follows:\n\n",
+			m.backups.preview.Time.Local().Format(time.DateTime),
+		)
+
+		content += renderDiffSection("Added entries", "+", addedStyle, diff.Added)
+		content += renderDiffSection("Removed entries", "-", removedStyle, diff.Removed)
+		content += renderDiffSection("Changed entries", "~", changedStyle, diff.Changed)
+
+		if len(diff.Added)+len(diff.Removed)+len(diff.Changed) == 0 {
+			content += "The snapshot contains the same entries as the current vault.\n"
+		}
+
+		content = strings.TrimRight(content, "\n")
+	}
+
+	finalRender := baseStyle.Render(content) + "\n"
+	finalRender += baseStyle.Render(m.Help.ShortHelpView(m.backups.helpBindings())) + "\n"
+
+	if m.backups.err != nil {
+		finalRender += errorStyle.Render(m.backups.err.Error()) + "\n"
+	}
+
+	if pwCopied {
+		finalRender += pwAdditive
+	}
+
+	return finalRender
+}
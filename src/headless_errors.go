@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The state of the error screen which is shown if the active vault could not be opened.
+// It explains what happened and offers ways to recover (e.g. restoring a backup).
+type errorView struct {
+	active bool
+	err    error
+	KeyMap errorKeyMap
+}
+
+type errorKeyMap struct {
+	Retry   key.Binding
+	Backups key.Binding
+	Vaults  key.Binding
+	Quit    key.Binding
+}
+
+// Returns a short title and an explanation of the given error which occurred while opening a vault.
+func explainError(err error) (string, string) {
+	switch {
+
+	case errors.Is(err, ErrWrongKey):
+		return "The vault could not be decrypted",
+			"Either the master password is wrong, the vault has been encrypted by another Windows account " +
+				"or the file has been modified. Try again or restore one of the backups."
+
+	case errors.Is(err, ErrNoMasterPw):
+		return "No master password has been entered",
+			"This vault is protected by a master password. Try again and enter it."
+
+	case errors.Is(err, ErrCorruptVault):
+		return "The vault is corrupt",
+			"The file is truncated or damaged, e.g. because it has been modified by another program. " +
+				"Restore one of the backups or the .bak file next to the vault."
+
+	case errors.Is(err, ErrUnsupportedVersion):
+		return "The vault has been written by a newer version of PwdMan",
+			"Update PwdMan in order to open this vault."
+
+	case errors.Is(err, ErrUnsupportedCipher):
+		return "The vault uses an unknown cipher",
+			"The vault has been written by a newer version of PwdMan or is corrupt. " +
+				"Update PwdMan or restore one of the backups."
+
+	case errors.Is(err, ErrUnsupportedPlatform):
+		return "The vault can't be opened on this platform",
+			"Open the vault on Windows and switch it to the portable backend (-backend portable) " +
+				"in order to use it on both Windows and Linux."
+
+	case errors.Is(err, ErrUndecodable):
+		return "The accounts could not be decoded",
+			"The vault could be decrypted, but the accounts stored within it can't be read. It has probably " +
+				"been written by a newer version of PwdMan. Update PwdMan or restore one of the backups."
+
+	case errors.Is(err, os.ErrPermission):
+		return "The vault could not be read",
+			"You are not allowed to read the vault. Check the permissions of the file."
+	}
+
+	return "The vault could not be opened", "An unexpected error occurred."
+}
+
+// Shows the error screen for the given error which occurred while opening the active vault.
+func (m model) showError(err error) (tea.Model, tea.Cmd) {
+	zero(&m.vaults.newPw)
+	m.vaults = vaultsView{}
+	m.loaded = false
+	m.table.Blur()
+
+	m.failure = errorView{
+		active: true,
+		err:    err,
+		KeyMap: errorKeyMap{
+			Retry: key.NewBinding(
+				key.WithKeys("enter"),
+				key.WithHelp("enter", "Try again"),
+			),
+			Backups: key.NewBinding(
+				key.WithKeys("ctrl+b"),
+				key.WithHelp("ctrl+b", "Backups"),
+			),
+			Vaults: key.NewBinding(
+				key.WithKeys("ctrl+o"),
+				key.WithHelp("ctrl+o", "Other vault"),
+			),
+			Quit: key.NewBinding(
+				key.WithKeys("ctrl+c", "esc"),
+				key.WithHelp("ctrl+c/esc", "Quit"),
+			),
+		},
+	}
+
+	return m, tea.ClearScreen
+}
+
+// Handles messages while the error screen is active.
+func (m model) updateError(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch {
+
+	case key.Matches(keyMsg, m.failure.KeyMap.Quit):
+		return m, tea.Quit
+
+	case key.Matches(keyMsg, m.failure.KeyMap.Retry):
+		m.failure = errorView{}
+		m, _ = m.startVaults(VAULTS_OPEN)
+
+		return m.openVault(vaultPath)
+
+	case key.Matches(keyMsg, m.failure.KeyMap.Backups):
+		// The error screen is shown again when the backups screen is closed without restoring
+		return m.startBackups()
+
+	case key.Matches(keyMsg, m.failure.KeyMap.Vaults):
+		m.failure = errorView{}
+		return m.startVaults(VAULTS_OPEN)
+	}
+
+	return m, nil
+}
+
+func (m model) viewError() string {
+	title, explanation := explainError(m.failure.err)
+
+	finalRender := pathStyle.Render("Vault: "+vaultPath) + "\n"
+	finalRender += errorStyle.Width(max(min(m.width-2, 100), 40)).Render(
+		title+"\n\n"+explanation+"\n\nDetails: "+m.failure.err.Error(),
+	) + "\n"
+	finalRender += baseStyle.Render(m.Help.ShortHelpView([]key.Binding{
+		m.failure.KeyMap.Retry,
+		m.failure.KeyMap.Backups,
+		m.failure.KeyMap.Vaults,
+		m.failure.KeyMap.Quit,
+	})) + "\n"
+
+	if pwCopied {
+		finalRender += pwAdditive
+	}
+
+	return finalRender
+}
+
+// Returns the notice shown above the accounts table while the vault is read-only.
+func readOnlyNotice(err error) string {
+	return "Read-only: " + err.Error() + ".\nChanges can't be saved. Restore a backup using ctrl+b or open the vault using a newer version of PwdMan."
+}
+
+// Returns whether the active vault is read-only because its accounts could only be decoded
+// partially (see "ErrUndecodable"). If so, the user is informed that changes can't be saved.
+func (m model) readOnly() bool {
+	if m.integrityErr == nil {
+		return false
+	}
+
+	showAdditive(errorStyle.Render("The vault is read-only, changes can't be saved!")+"\n", 5*time.Second)
+
+	return true
+}
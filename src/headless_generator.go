@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The fields of the generator screen in the order of their inputs (see "generatorView.inputs").
+const (
+	GENERATOR_MIN_LENGTH = iota
+	GENERATOR_MAX_LENGTH
+	GENERATOR_MIN_LOWER
+	GENERATOR_MIN_UPPER
+	GENERATOR_MIN_DIGITS
+	GENERATOR_MIN_SYMBOLS
+	GENERATOR_SYMBOLS
+	GENERATOR_MAX_REPEAT
+	GENERATOR_WORDS
+	GENERATOR_SEPARATOR
+	GENERATOR_CAPITALIZE
+	GENERATOR_ADD_DIGITS
+	GENERATOR_ADD_SYMBOLS
+	GENERATOR_FIELDS
+)
+
+// The fields of the generator screen shown in the respective mode (see "pwPolicy.Mode").
+var (
+	characterFields = []int{
+		GENERATOR_MIN_LENGTH, GENERATOR_MAX_LENGTH, GENERATOR_MIN_LOWER, GENERATOR_MIN_UPPER,
+		GENERATOR_MIN_DIGITS, GENERATOR_MIN_SYMBOLS, GENERATOR_SYMBOLS, GENERATOR_MAX_REPEAT,
+	}
+	passphraseFields = []int{
+		GENERATOR_WORDS, GENERATOR_SEPARATOR, GENERATOR_CAPITALIZE, GENERATOR_ADD_DIGITS,
+		GENERATOR_ADD_SYMBOLS, GENERATOR_SYMBOLS,
+	}
+)
+
+// The labels of the fields of the generator screen.
+var generatorLabels = [GENERATOR_FIELDS]string{
+	"Minimum length",
+	"Maximum length",
+	"Minimum lowercase letters",
+	"Minimum uppercase letters",
+	"Minimum digits",
+	"Minimum symbols",
+	"Symbols",
+	"Maximum identical characters in a row (0 = any)",
+	"Words",
+	"Separator",
+	"Capitalization (" + strings.Join(capitalizations, ", ") + ")",
+	"Digits appended to random words",
+	"Symbols appended to random words",
+}
+
+var policySavedAdditive = baseStyle.Foreground(lipgloss.Color("127")).Render("Saved as the default policy!") + "\n"
+
+// The state of the generator screen which is opened from the edit screen. It shows the policy
+// of the password generator used for the selected account (see "pwPolicy") which can be changed
+// before generating a new password.
+type generatorView struct {
+	active            bool
+	inputs            [GENERATOR_FIELDS]textinput.Model
+	passphrase        bool
+	excludeLookAlikes bool
+	// The focused field (see "GENERATOR_MIN_LENGTH" etc.)
+	focus  int
+	err    error
+	KeyMap generatorKeyMap
+}
+
+type generatorKeyMap struct {
+	Generate    key.Binding
+	Next        key.Binding
+	Prev        key.Binding
+	Passphrase  key.Binding
+	LookAlikes  key.Binding
+	SaveDefault key.Binding
+	Reset       key.Binding
+	Back        key.Binding
+	Quit        key.Binding
+}
+
+// Fills the inputs of the generator screen with the given policy.
+func (gv *generatorView) setPolicy(p pwPolicy) {
+	values := [GENERATOR_FIELDS]string{
+		GENERATOR_MIN_LENGTH:  strconv.Itoa(p.MinLength),
+		GENERATOR_MAX_LENGTH:  strconv.Itoa(p.MaxLength),
+		GENERATOR_MIN_LOWER:   strconv.Itoa(p.MinLower),
+		GENERATOR_MIN_UPPER:   strconv.Itoa(p.MinUpper),
+		GENERATOR_MIN_DIGITS:  strconv.Itoa(p.MinDigits),
+		GENERATOR_MIN_SYMBOLS: strconv.Itoa(p.MinSymbols),
+		GENERATOR_SYMBOLS:     p.Symbols,
+		GENERATOR_MAX_REPEAT:  strconv.Itoa(p.MaxRepeat),
+		GENERATOR_WORDS:       strconv.Itoa(p.Passphrase.Words),
+		GENERATOR_SEPARATOR:   p.Passphrase.Separator,
+		GENERATOR_CAPITALIZE:  p.Passphrase.Capitalize,
+		GENERATOR_ADD_DIGITS:  strconv.Itoa(p.Passphrase.Digits),
+		GENERATOR_ADD_SYMBOLS: strconv.Itoa(p.Passphrase.Symbols),
+	}
+
+	for i := range gv.inputs {
+		gv.inputs[i].SetValue(values[i])
+	}
+
+	gv.passphrase = p.Mode == PW_MODE_PASSPHRASE
+	gv.excludeLookAlikes = p.ExcludeLookAlikes
+}
+
+// Returns the fields shown in the current mode.
+func (gv generatorView) fields() []int {
+	if gv.passphrase {
+		return passphraseFields
+	}
+
+	return characterFields
+}
+
+// Returns the policy entered on the generator screen or an error if it is invalid.
+func (gv generatorView) policy() (pwPolicy, error) {
+	p := pwPolicy{
+		Mode:              PW_MODE_CHARACTERS,
+		Symbols:           gv.inputs[GENERATOR_SYMBOLS].Value(),
+		ExcludeLookAlikes: gv.excludeLookAlikes,
+		Passphrase: passphrasePolicy{
+			Separator:  gv.inputs[GENERATOR_SEPARATOR].Value(),
+			Capitalize: strings.TrimSpace(gv.inputs[GENERATOR_CAPITALIZE].Value()),
+		},
+	}
+
+	if gv.passphrase {
+		p.Mode = PW_MODE_PASSPHRASE
+	}
+
+	for field, target := range map[int]*int{
+		GENERATOR_MIN_LENGTH:  &p.MinLength,
+		GENERATOR_MAX_LENGTH:  &p.MaxLength,
+		GENERATOR_MIN_LOWER:   &p.MinLower,
+		GENERATOR_MIN_UPPER:   &p.MinUpper,
+		GENERATOR_MIN_DIGITS:  &p.MinDigits,
+		GENERATOR_MIN_SYMBOLS: &p.MinSymbols,
+		GENERATOR_MAX_REPEAT:  &p.MaxRepeat,
+		GENERATOR_WORDS:       &p.Passphrase.Words,
+		GENERATOR_ADD_DIGITS:  &p.Passphrase.Digits,
+		GENERATOR_ADD_SYMBOLS: &p.Passphrase.Symbols,
+	} {
+		n, err := strconv.Atoi(strings.TrimSpace(gv.inputs[field].Value()))
+		if err != nil {
+			return p, fmt.Errorf("%s must be a number", generatorLabels[field])
+		}
+
+		*target = n
+	}
+
+	return p, p.validate()
+}
+
+// Moves the focus to the given field of the generator screen.
+func (gv *generatorView) focusInput(focus int) tea.Cmd {
+	gv.inputs[gv.focus].Blur()
+	gv.inputs[gv.focus].PromptStyle = lipgloss.NewStyle()
+
+	gv.focus = focus
+	gv.inputs[gv.focus].PromptStyle = focusedStyle
+
+	return gv.inputs[gv.focus].Focus()
+}
+
+// Moves the focus by the given number of fields shown in the current mode.
+func (gv *generatorView) moveFocus(delta int) tea.Cmd {
+	fields := gv.fields()
+	position := max(slices.Index(fields, gv.focus), 0)
+
+	return gv.focusInput(fields[(position+delta+len(fields))%len(fields)])
+}
+
+// Opens the generator screen showing the policy of the selected account.
+func (m model) startGenerator() (tea.Model, tea.Cmd) {
+	m.generator = generatorView{
+		active: true,
+		KeyMap: generatorKeyMap{
+			Generate: key.NewBinding(
+				key.WithKeys("enter"),
+				key.WithHelp("enter", "Generate password"),
+			),
+			Next: key.NewBinding(
+				key.WithKeys("tab", "down"),
+				key.WithHelp("tab/↓", "Next field"),
+			),
+			Prev: key.NewBinding(
+				key.WithKeys("shift+tab", "up"),
+				key.WithHelp("shift+tab/↑", "Previous field"),
+			),
+			Passphrase: key.NewBinding(
+				key.WithKeys("ctrl+p"),
+				key.WithHelp("ctrl+p", "Passphrase on/off"),
+			),
+			LookAlikes: key.NewBinding(
+				key.WithKeys("ctrl+l"),
+				key.WithHelp("ctrl+l", "Exclude look-alikes on/off"),
+			),
+			SaveDefault: key.NewBinding(
+				key.WithKeys("ctrl+s"),
+				key.WithHelp("ctrl+s", "Save as default policy"),
+			),
+			Reset: key.NewBinding(
+				key.WithKeys("ctrl+r"),
+				key.WithHelp("ctrl+r", "Reset to default policy"),
+			),
+			Back: key.NewBinding(
+				key.WithKeys("esc"),
+				key.WithHelp("esc", "Back"),
+			),
+			Quit: key.NewBinding(
+				key.WithKeys("ctrl+c"),
+				key.WithHelp("ctrl+c", "Quit"),
+			),
+		},
+	}
+
+	for i := range m.generator.inputs {
+		m.generator.inputs[i] = textinput.New()
+		m.generator.inputs[i].Width = 32
+	}
+
+	if m.policy != nil {
+		m.generator.setPolicy(*m.policy)
+	} else {
+		m.generator.setPolicy(userSettings.Generator)
+	}
+
+	return m, tea.Batch(tea.ClearScreen, m.generator.focusInput(m.generator.fields()[0]))
+}
+
+// Handles messages while the generator screen is active.
+func (m model) updateGenerator(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+
+		case key.Matches(msg, m.generator.KeyMap.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, m.generator.KeyMap.Back):
+			m.generator = generatorView{}
+			return m, tea.ClearScreen
+
+		case key.Matches(msg, m.generator.KeyMap.Next):
+			return m, m.generator.moveFocus(1)
+
+		case key.Matches(msg, m.generator.KeyMap.Prev):
+			return m, m.generator.moveFocus(-1)
+
+		case key.Matches(msg, m.generator.KeyMap.Passphrase):
+			m.generator.passphrase = !m.generator.passphrase
+			m.generator.err = nil
+
+			return m, tea.Batch(tea.ClearScreen, m.generator.focusInput(m.generator.fields()[0]))
+
+		case key.Matches(msg, m.generator.KeyMap.LookAlikes):
+			m.generator.excludeLookAlikes = !m.generator.excludeLookAlikes
+			return m, nil
+
+		case key.Matches(msg, m.generator.KeyMap.Reset):
+			m.generator.setPolicy(userSettings.Generator)
+			m.generator.err = nil
+
+			return m, tea.Batch(tea.ClearScreen, m.generator.focusInput(m.generator.fields()[0]))
+
+		case key.Matches(msg, m.generator.KeyMap.SaveDefault):
+			p, err := m.generator.policy()
+			if m.generator.err = err; err != nil {
+				return m, nil
+			}
+
+			userSettings.Generator = p
+
+			if m.generator.err = saveSettings(); m.generator.err == nil {
+				showAdditive(policySavedAdditive, 3*time.Second)
+			}
+
+			return m, nil
+
+		case key.Matches(msg, m.generator.KeyMap.Generate):
+			p, err := m.generator.policy()
+			if m.generator.err = err; err != nil {
+				return m, nil
+			}
+
+			pwBuf, err := generatePw(p)
+			defer zero(pwBuf)
+
+			if m.generator.err = err; err != nil {
+				return m, nil
+			}
+
+			m.inpPw.SetValue(string(*pwBuf))
+
+			// The account only keeps its own policy if it differs from the default policy
+			m.policy = nil
+
+			if p != userSettings.Generator {
+				m.policy = &p
+			}
+
+			m.generator = generatorView{}
+
+			return m, tea.ClearScreen
+		}
+	}
+
+	m.generator.inputs[m.generator.focus], cmd = m.generator.inputs[m.generator.focus].Update(msg)
+
+	return m, cmd
+}
+
+func (m model) viewGenerator() string {
+	var sb strings.Builder
+
+	if m.generator.passphrase {
+		sb.WriteString("Passphrase generator (EFF wordlist)\n\n")
+	} else {
+		sb.WriteString("Password generator\n\n")
+	}
+
+	for _, field := range m.generator.fields() {
+		sb.WriteString(generatorLabels[field] + "\n" + m.generator.inputs[field].View() + "\n\n")
+	}
+
+	lookAlikes := "no"
+
+	if m.generator.excludeLookAlikes {
+		lookAlikes = "yes (" + LOOK_ALIKE_CHARS + ")"
+	}
+
+	sb.WriteString("Exclude look-alike characters: " + lookAlikes)
+
+	finalRender := baseStyle.Render(sb.String()) + "\n"
+	finalRender += baseStyle.Render(m.Help.FullHelpView([][]key.Binding{
+		{m.generator.KeyMap.Generate, m.generator.KeyMap.Next, m.generator.KeyMap.Prev, m.generator.KeyMap.Passphrase, m.generator.KeyMap.LookAlikes},
+		{m.generator.KeyMap.SaveDefault, m.generator.KeyMap.Reset, m.generator.KeyMap.Back, m.generator.KeyMap.Quit},
+	})) + "\n"
+
+	if m.generator.err != nil {
+		finalRender += errorStyle.Render(m.generator.err.Error()) + "\n"
+	}
+
+	if pwCopied {
+		finalRender += pwAdditive
+	}
+
+	return finalRender
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.design/x/clipboard"
+)
+
+// The state of the password history screen which lists the previous passwords of the selected
+// account (see "account.History"). It is opened from the edit screen.
+type historyView struct {
+	active   bool
+	table    table.Model
+	entries  []pwHistoryEntry
+	revealed bool
+	KeyMap   historyKeyMap
+}
+
+type historyKeyMap struct {
+	Use    key.Binding
+	CopyPw key.Binding
+	ShowPw key.Binding
+	Back   key.Binding
+	Quit   key.Binding
+}
+
+// Returns the columns of the history table for the given width of the terminal window.
+func historyColumns(width int) []table.Column {
+	return []table.Column{
+		{Title: "Replaced at", Width: 20},
+		{Title: "Password", Width: max(width/2, 20)},
+	}
+}
+
+// Adapts the size of the history table to the size of the terminal window.
+func (hv *historyView) resize(width int, height int) {
+	hv.table.SetColumns(historyColumns(width))
+	hv.table.SetHeight(height / 3 * 2)
+}
+
+// Updates the rows of the history table, showing the passwords only if they have been revealed.
+func (hv *historyView) refresh() {
+	rows := []table.Row{}
+
+	for _, e := range hv.entries {
+		pw := MASKED_PW
+
+		if hv.revealed {
+			pw = e.Pw
+		}
+
+		rows = append(rows, table.Row{e.Replaced.Local().Format(time.DateTime), pw})
+	}
+
+	hv.table.SetRows(rows)
+}
+
+// Opens the password history of the selected account.
+func (m model) startHistory() (tea.Model, tea.Cmd) {
+	t := table.New(
+		table.WithColumns(historyColumns(m.width)),
+		table.WithFocused(true),
+	)
+	t.SetStyles(m.tableStyles)
+
+	m.history = historyView{
+		active:  true,
+		table:   t,
+		entries: m.selected.History,
+		KeyMap: historyKeyMap{
+			Use: key.NewBinding(
+				key.WithKeys("enter", "tab"),
+				key.WithHelp("enter/tab", "Use this password"),
+			),
+			CopyPw: key.NewBinding(
+				key.WithKeys("ctrl+q"),
+				key.WithHelp("ctrl+q", "Copy password"),
+			),
+			ShowPw: key.NewBinding(
+				key.WithKeys("ctrl+r"),
+				key.WithHelp("ctrl+r", "Show / hide passwords"),
+			),
+			Back: key.NewBinding(
+				key.WithKeys("esc", "shift+tab"),
+				key.WithHelp("esc/shift+tab", "Back"),
+			),
+			Quit: key.NewBinding(
+				key.WithKeys("ctrl+c"),
+				key.WithHelp("ctrl+c", "Quit"),
+			),
+		},
+	}
+	m.history.refresh()
+	m.history.resize(m.width, m.height)
+
+	return m, tea.ClearScreen
+}
+
+// Handles messages while the password history screen is active.
+func (m model) updateHistory(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+
+		case key.Matches(msg, m.history.KeyMap.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, m.history.KeyMap.Back):
+			m.history = historyView{}
+			return m, tea.ClearScreen
+
+		case key.Matches(msg, m.history.KeyMap.ShowPw):
+			m.history.revealed = !m.history.revealed
+			m.history.refresh()
+
+		case key.Matches(msg, m.history.KeyMap.CopyPw):
+			if len(m.history.entries) == 0 {
+				break
+			}
+
+			if clipboardErr != nil {
+				showAdditive(errorStyle.Render("The clipboard is unavailable: "+clipboardErr.Error())+"\n", 10*time.Second)
+				break
+			}
+
+			clipboard.Write(clipboard.FmtText, []byte(m.history.entries[m.history.table.Cursor()].Pw))
+			showAdditive(actualPwAdditive, 3*time.Second)
+
+		case key.Matches(msg, m.history.KeyMap.Use):
+			if len(m.history.entries) == 0 {
+				break
+			}
+
+			// The password is only changed once the entry is saved, which keeps the current one in the history
+			m.inpPw.SetValue(m.history.entries[m.history.table.Cursor()].Pw)
+			m.history = historyView{}
+
+			return m, tea.ClearScreen
+		}
+	}
+
+	m.history.table, cmd = m.history.table.Update(msg)
+
+	return m, cmd
+}
+
+func (m model) viewHistory() string {
+	var content string
+
+	if len(m.history.entries) == 0 {
+		content = "There are no previous passwords yet. A password is kept here every time it is changed."
+	} else {
+		content = "Password history of " + m.selected.Service + "\n\n" + m.history.table.View()
+	}
+
+	finalRender := baseStyle.Render(content) + "\n"
+	finalRender += baseStyle.Render(m.Help.ShortHelpView([]key.Binding{
+		m.history.KeyMap.Use, m.history.KeyMap.CopyPw, m.history.KeyMap.ShowPw, m.history.KeyMap.Back, m.history.KeyMap.Quit,
+	})) + "\n"
+
+	if pwCopied {
+		finalRender += pwAdditive
+	}
+
+	return finalRender
+}
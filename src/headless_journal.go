@@ -0,0 +1,139 @@
+package main
+
+import (
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The state of the journal screen which lists the recorded changes of the active vault,
+// newest first (see "journal.go").
+type journalView struct {
+	active  bool
+	table   table.Model
+	entries []journalEntry
+	KeyMap  journalKeyMap
+}
+
+type journalKeyMap struct {
+	Back key.Binding
+	Quit key.Binding
+}
+
+// Returns the columns of the journal table for the given width of the terminal window.
+func journalColumns(width int) []table.Column {
+	return []table.Column{
+		{Title: "Time", Width: 20},
+		{Title: "Author", Width: max(width/6, 12)},
+		{Title: "Action", Width: 8},
+		{Title: "Entry", Width: max(width/5, 12)},
+		{Title: "Details", Width: max(width/3, 20)},
+	}
+}
+
+// Adapts the size of the journal table to the size of the terminal window.
+func (jv *journalView) resize(width int, height int) {
+	jv.table.SetColumns(journalColumns(width))
+	jv.table.SetHeight(height / 3 * 2)
+}
+
+// Opens the journal of the active vault.
+func (m model) startJournal() (tea.Model, tea.Cmd) {
+	entries, err := readJournal()
+	if err != nil {
+		showAdditive(errorStyle.Render("Could not read the journal: "+err.Error())+"\n", 10*time.Second)
+		return m, nil
+	}
+
+	slices.Reverse(entries)
+
+	rows := []table.Row{}
+
+	for _, e := range entries {
+		entry := e.Service
+
+		if len(e.User) > 0 {
+			entry += " (" + e.User + ")"
+		}
+
+		rows = append(rows, table.Row{
+			e.Time.Local().Format(time.DateTime),
+			e.Author,
+			e.Action,
+			entry,
+			journalDetails(e),
+		})
+	}
+
+	t := table.New(
+		table.WithColumns(journalColumns(m.width)),
+		table.WithRows(rows),
+		table.WithFocused(true),
+	)
+	t.SetStyles(m.tableStyles)
+
+	m.journal = journalView{
+		active:  true,
+		table:   t,
+		entries: entries,
+		KeyMap: journalKeyMap{
+			Back: key.NewBinding(
+				key.WithKeys("esc", "shift+tab"),
+				key.WithHelp("esc/shift+tab", "Back"),
+			),
+			Quit: key.NewBinding(
+				key.WithKeys("ctrl+c"),
+				key.WithHelp("ctrl+c", "Quit"),
+			),
+		},
+	}
+	m.journal.resize(m.width, m.height)
+	m.table.Blur()
+
+	return m, tea.ClearScreen
+}
+
+// Handles messages while the journal screen is active.
+func (m model) updateJournal(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+
+		case key.Matches(msg, m.journal.KeyMap.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, m.journal.KeyMap.Back):
+			m.journal = journalView{}
+			m.table.Focus()
+
+			return m, tea.ClearScreen
+		}
+	}
+
+	m.journal.table, cmd = m.journal.table.Update(msg)
+
+	return m, cmd
+}
+
+func (m model) viewJournal() string {
+	var content string
+
+	if len(m.journal.entries) == 0 {
+		content = "The journal is empty. Every change of an entry is recorded here."
+	} else {
+		content = "Journal\n\n" + m.journal.table.View()
+	}
+
+	finalRender := baseStyle.Render(content) + "\n"
+	finalRender += baseStyle.Render(m.Help.ShortHelpView([]key.Binding{m.journal.KeyMap.Back, m.journal.KeyMap.Quit})) + "\n"
+
+	if pwCopied {
+		finalRender += pwAdditive
+	}
+
+	return finalRender
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The stages of the master password change screen.
+const (
+	REKEY_INACTIVE = iota
+	REKEY_CURRENT
+	REKEY_NEW
+	REKEY_CONFIRM
+)
+
+var (
+	// Whether the master password can be changed. This is only the case
+	// if the vault is protected by a master password.
+	rekeyable       = false
+	rekeyedAdditive = baseStyle.Foreground(lipgloss.Color("127")).Render("Master password changed!") + "\n"
+)
+
+// Opens the master password change screen.
+func (m model) startRekey() (tea.Model, tea.Cmd) {
+	m.rekeyStage = REKEY_CURRENT
+	m.prompt = newPwPrompt("Enter your current master password", "")
+	m.table.Blur()
+
+	return m, m.prompt.Init()
+}
+
+// Leaves the master password change screen and returns to the table.
+func (m model) stopRekey() model {
+	zero(&m.newPw)
+
+	m.newPw = nil
+	m.rekeyStage = REKEY_INACTIVE
+	m.table.Focus()
+
+	return m
+}
+
+// Handles messages while the master password change screen is active. The user has to enter
+// the current master password first, followed by the new one which has to be confirmed.
+func (m model) updateRekey(msg tea.Msg) (tea.Model, tea.Cmd) {
+	res, cmd := m.prompt.Update(msg)
+	m.prompt = res.(pwPromptModel)
+
+	if m.prompt.cancelled {
+		return m.stopRekey(), nil
+	}
+
+	if !m.prompt.submitted {
+		return m, cmd
+	}
+
+	pw := []byte(m.prompt.input.Value())
+
+	switch m.rekeyStage {
+
+	case REKEY_CURRENT:
+		if subtle.ConstantTimeCompare(pw, masterPw) != 1 {
+			m.prompt = newPwPrompt("Enter your current master password", "Wrong master password!")
+		} else {
+			m.rekeyStage = REKEY_NEW
+			m.prompt = newPwPrompt("Choose a new master password", "")
+		}
+
+		zero(&pw)
+
+	case REKEY_NEW:
+		m.newPw = pw
+		m.rekeyStage = REKEY_CONFIRM
+		m.prompt = newPwPrompt("Confirm your new master password", "")
+
+	case REKEY_CONFIRM:
+		if !slices.Equal(pw, m.newPw) {
+			zero(&pw)
+			zero(&m.newPw)
+
+			m.newPw = nil
+			m.rekeyStage = REKEY_NEW
+			m.prompt = newPwPrompt("Choose a new master password", "The passwords did not match!")
+
+			break
+		}
+
+		zero(&pw)
+
+		skipped, err := rekeyVault(m.newPw)
+
+		if err == nil {
+			// The new password is now owned by "masterPw"
+			m.newPw = nil
+		}
+
+		m = m.stopRekey()
+
+		if err != nil {
+			showAdditive(saveErrorAdditive(err), 10*time.Second)
+		} else if len(skipped) > 0 {
+			showAdditive(rekeyedAdditive+errorStyle.Render(skippedFilesWarning(skipped))+"\n", 10*time.Second)
+		} else {
+			showAdditive(rekeyedAdditive, 3*time.Second)
+		}
+
+		return m, nil
+	}
+
+	return m, m.prompt.Init()
+}
+
+// Changes the master password of the vault without starting the TUI (see the -rekey flag).
+// Legacy vaults (e.g. vaults protected by the SHA-256 hash of /etc/machine-id) are migrated
+// to the current vault format protected by the new master password.
+func runRekey() {
+	encryptedData, err := readFile(vaultPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not read the vault:", err)
+		os.Exit(1)
+	}
+
+	exists := len(encryptedData) > 0
+	zero(&encryptedData)
+
+	if !exists {
+		fmt.Fprintln(os.Stderr, "There is no vault to re-key at", vaultPath)
+		os.Exit(1)
+	}
+
+	if backend == BACKEND_NATIVE && !nativeUsesMasterPw {
+		fmt.Fprintf(os.Stderr, "The native backend does not use a master password. Use -backend %s to protect the vault by a master password.\n", BACKEND_PORTABLE)
+		os.Exit(2)
+	}
+
+	if ok, err := unlockVault(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not read the vault:", err)
+		os.Exit(1)
+	} else if !ok {
+		return
+	}
+
+	newPw, ok := chooseMasterPw("Choose a new master password")
+	if !ok {
+		return
+	}
+
+	skipped, err := rekeyVault(newPw)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not save the vault:", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("The master password has been changed.")
+
+	if len(skipped) > 0 {
+		fmt.Fprintln(os.Stderr, "Warning:", skippedFilesWarning(skipped))
+	}
+}
+
+// Returns the warning shown if some files of the vault couldn't be re-encrypted (see "rekeyVault()").
+func skippedFilesWarning(skipped []string) string {
+	return "These files could not be read and still use the previous master password, " +
+		"delete them if it has been compromised: " + strings.Join(skipped, ", ")
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The state of the reuse screen which lists the accounts of the active vault sharing the same
+// or nearly the same password (see "reuse.go"). The passwords themselves are never shown.
+type reuseView struct {
+	active bool
+	table  table.Model
+	groups []reuseGroup
+	KeyMap reuseKeyMap
+}
+
+type reuseKeyMap struct {
+	Back key.Binding
+	Quit key.Binding
+}
+
+// Returns the columns of the reuse table for the given width of the terminal window.
+func reuseColumns(width int) []table.Column {
+	return []table.Column{
+		{Title: "Group", Width: 5},
+		{Title: "Kind", Width: 36},
+		{Title: "Service", Width: max(width/5, 12)},
+		{Title: "User", Width: max(width/5, 12)},
+		{Title: "Modified at", Width: 20},
+	}
+}
+
+// Adapts the size of the reuse table to the size of the terminal window.
+func (rv *reuseView) resize(width int, height int) {
+	rv.table.SetColumns(reuseColumns(width))
+	rv.table.SetHeight(height / 3 * 2)
+}
+
+// Opens the reuse screen for the accounts of the active vault.
+func (m model) startReuse() (tea.Model, tea.Cmd) {
+	t := table.New(
+		table.WithColumns(reuseColumns(m.width)),
+		table.WithFocused(true),
+	)
+	t.SetStyles(m.tableStyles)
+
+	m.reuse = reuseView{
+		active: true,
+		table:  t,
+		groups: findReuse((*m.accounts)[1:]),
+		KeyMap: reuseKeyMap{
+			Back: key.NewBinding(
+				key.WithKeys("esc", "shift+tab"),
+				key.WithHelp("esc/shift+tab", "Back"),
+			),
+			Quit: key.NewBinding(
+				key.WithKeys("ctrl+c"),
+				key.WithHelp("ctrl+c", "Quit"),
+			),
+		},
+	}
+
+	rows := []table.Row{}
+
+	for i, g := range m.reuse.groups {
+		for _, a := range g.Accounts {
+			rows = append(rows, table.Row{
+				strconv.Itoa(i + 1),
+				g.describe(),
+				a.Service,
+				a.User,
+				a.Modified.Local().Format(time.DateTime),
+			})
+		}
+	}
+
+	m.reuse.table.SetRows(rows)
+	m.reuse.resize(m.width, m.height)
+	m.table.Blur()
+
+	return m, tea.ClearScreen
+}
+
+// Handles messages while the reuse screen is active.
+func (m model) updateReuse(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+
+		case key.Matches(msg, m.reuse.KeyMap.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, m.reuse.KeyMap.Back):
+			m.reuse = reuseView{}
+			m.table.Focus()
+
+			return m, tea.ClearScreen
+		}
+	}
+
+	m.reuse.table, cmd = m.reuse.table.Update(msg)
+
+	return m, cmd
+}
+
+func (m model) viewReuse() string {
+	var content string
+
+	if len(m.reuse.groups) == 0 {
+		content = "No entries share the same or similar passwords."
+	} else {
+		content = "Password reuse\n\n" + m.reuse.table.View()
+	}
+
+	finalRender := baseStyle.Render(content) + "\n"
+	finalRender += baseStyle.Render(m.Help.ShortHelpView([]key.Binding{
+		m.reuse.KeyMap.Back, m.reuse.KeyMap.Quit,
+	})) + "\n"
+
+	identical, similar := 0, 0
+
+	for _, g := range m.reuse.groups {
+		if g.Kind == REUSE_IDENTICAL {
+			identical += len(g.Accounts)
+		} else {
+			similar += len(g.Accounts)
+		}
+	}
+
+	if identical+similar > 0 {
+		finalRender += errorStyle.Render(fmt.Sprintf("%d entries share their password and %d entries use similar passwords. "+
+			"If one of them is breached, the others are at risk as well.", identical, similar)) + "\n"
+	}
+
+	if pwCopied {
+		finalRender += pwAdditive
+	}
+
+	return finalRender
+}
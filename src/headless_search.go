@@ -0,0 +1,160 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The key bindings of the search mode.
+var (
+	searchApply = key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "Apply filter"),
+	)
+	searchClear = key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "Clear filter"),
+	)
+	searchUp = key.NewBinding(
+		key.WithKeys("up"),
+		key.WithHelp("↑", "Up"),
+	)
+	searchDown = key.NewBinding(
+		key.WithKeys("down"),
+		key.WithHelp("↓", "Down"),
+	)
+)
+
+// Returns whether the given text contains all characters of the given pattern in the same
+// order (case-insensitive). For example, "gthb" matches "GitHub".
+func fuzzyMatch(pattern string, text string) bool {
+	runes := []rune(strings.ToLower(pattern))
+	if len(runes) == 0 {
+		return true
+	}
+
+	i := 0
+
+	for _, r := range strings.ToLower(text) {
+		if r == runes[i] {
+			i++
+
+			if i == len(runes) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Returns whether the given account matches the given query. Every word of the query
+// has to match (see "fuzzyMatch()") the service, description, user or notes of the account.
+func accountMatches(a account, query string) bool {
+	for word := range strings.FieldsSeq(query) {
+		if !fuzzyMatch(word, a.Service) && !fuzzyMatch(word, a.Description) &&
+			!fuzzyMatch(word, a.User) && !fuzzyMatch(word, a.Notes) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns a new input for the search query.
+func newSearchInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "/ "
+	ti.Placeholder = "Search service, description, user or notes"
+	ti.Width = 48
+	ti.PromptStyle = focusedStyle
+
+	return ti
+}
+
+// Updates the rows of the accounts table, e.g. after the accounts or the search query changed.
+// The cursor is kept within the rows, as the table itself doesn't do so.
+func (m *model) refreshRows() {
+	m.table.SetRows(accountRows(*m.accounts, m.search.Value()))
+
+	if rows := len(m.table.Rows()); m.table.Cursor() >= rows {
+		m.table.SetCursor(rows - 1)
+	}
+}
+
+// Enters the search mode in which the accounts table is filtered while typing.
+func (m model) startSearch() (tea.Model, tea.Cmd) {
+	m.searching = true
+	m.table.Blur()
+
+	return m, m.search.Focus()
+}
+
+// Leaves the search mode. The filter stays applied unless clear is true.
+func (m model) stopSearch(clear bool) (tea.Model, tea.Cmd) {
+	m.searching = false
+	m.search.Blur()
+
+	if clear {
+		m.search.SetValue("")
+		m.refreshRows()
+	}
+
+	m.table.Focus()
+
+	return m, nil
+}
+
+// Handles messages while the search mode is active.
+func (m model) updateSearch(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+
+		case key.Matches(msg, searchApply):
+			return m.stopSearch(false)
+
+		case key.Matches(msg, searchClear):
+			return m.stopSearch(true)
+
+		case key.Matches(msg, searchUp):
+			m.table.MoveUp(1)
+			return m, nil
+
+		case key.Matches(msg, searchDown):
+			m.table.MoveDown(1)
+			return m, nil
+		}
+	}
+
+	query := m.search.Value()
+
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+
+	if m.search.Value() != query {
+		m.refreshRows()
+
+		// Moves the cursor to the first match, the first row is always the "New" entry
+		m.table.SetCursor(min(1, len(m.table.Rows())-1))
+	}
+
+	return m, cmd
+}
+
+// Renders the search input (if a filter has been entered) shown above the accounts table.
+func (m model) viewSearch() string {
+	if !m.searching && len(m.search.Value()) == 0 {
+		return ""
+	}
+
+	content := m.search.View()
+
+	if m.searching {
+		content += "\n" + m.Help.ShortHelpView([]key.Binding{searchApply, searchClear, searchUp, searchDown})
+	}
+
+	return baseStyle.Render(content) + "\n"
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The columns the accounts table can be sorted by, selected using the keys 1-5 in that order.
+const (
+	SORT_SERVICE     = "service"
+	SORT_DESCRIPTION = "description"
+	SORT_USER        = "user"
+	SORT_MODIFIED    = "modified"
+	SORT_LAST_USED   = "lastUsed"
+)
+
+var sortColumns = []string{SORT_SERVICE, SORT_DESCRIPTION, SORT_USER, SORT_MODIFIED, SORT_LAST_USED}
+
+// The names of the sort columns shown above the accounts table.
+var sortColumnNames = map[string]string{
+	SORT_SERVICE:     "service",
+	SORT_DESCRIPTION: "description",
+	SORT_USER:        "user",
+	SORT_MODIFIED:    "last modified",
+	SORT_LAST_USED:   "last used",
+}
+
+// Compares two accounts by the given column in ascending order. Texts are compared
+// case-insensitively. Accounts which have never been used are sorted first by SORT_LAST_USED.
+func compareAccounts(a account, b account, column string) int {
+	switch column {
+
+	case SORT_SERVICE:
+		return cmp.Compare(strings.ToLower(a.Service), strings.ToLower(b.Service))
+
+	case SORT_DESCRIPTION:
+		return cmp.Compare(strings.ToLower(a.Description), strings.ToLower(b.Description))
+
+	case SORT_USER:
+		return cmp.Compare(strings.ToLower(a.User), strings.ToLower(b.User))
+
+	case SORT_MODIFIED:
+		return a.Modified.Compare(b.Modified)
+
+	case SORT_LAST_USED:
+		return a.LastUsed.Compare(b.LastUsed)
+	}
+
+	return 0
+}
+
+// Sorts the given rows of the accounts table according to the given sort settings. The first row
+// (the "New" entry) stays at the top. Accounts which compare equal keep their order.
+func sortRows(rows []table.Row, accounts []account, s sortSettings) {
+	if len(rows) < 2 || len(s.Column) == 0 {
+		return
+	}
+
+	byID := map[string]account{}
+	for _, a := range accounts {
+		byID[a.ID] = a
+	}
+
+	slices.SortStableFunc(rows[1:], func(a table.Row, b table.Row) int {
+		c := compareAccounts(byID[a[0]], byID[b[0]], s.Column)
+
+		if s.Descending {
+			return -c
+		}
+
+		return c
+	})
+}
+
+// Sorts the accounts table by the given column (see "sortColumns"). If the table is already
+// sorted by that column, the order is reversed. An empty column restores the order in which
+// the accounts have been added. The order is saved in the user settings.
+func (m *model) sortBy(column string) {
+	if len(column) > 0 && column == userSettings.Sort.Column {
+		userSettings.Sort.Descending = !userSettings.Sort.Descending
+	} else {
+		userSettings.Sort = sortSettings{Column: column}
+	}
+
+	m.refreshRows()
+
+	if err := saveSettings(); err != nil {
+		showAdditive(errorStyle.Render("Could not save the settings: "+err.Error())+"\n", 10*time.Second)
+	}
+}
+
+// Describes the current order of the accounts table (shown next to the path of the vault).
+func viewSort() string {
+	name, found := sortColumnNames[userSettings.Sort.Column]
+	if !found {
+		return ""
+	}
+
+	arrow := "▲"
+
+	if userSettings.Sort.Descending {
+		arrow = "▼"
+	}
+
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(" · Sorted by " + name + " " + arrow)
+}
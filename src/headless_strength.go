@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The colors of the strength meter for every score (see "strength.Score").
+var scoreColors = []lipgloss.Color{"160", "202", "220", "112", "40"}
+
+// The number of cells of the strength meter, 2 cells are filled per score.
+const STRENGTH_METER_WIDTH = 8
+
+// Renders the strength meter of the password entered on the edit screen (see "estimateStrength()").
+// The service and the user are taken into account, as attackers are likely to try them first.
+func (m model) viewStrength() string {
+	pw := m.inpPw.Value()
+
+	if len(pw) == 0 {
+		return ""
+	}
+
+	s := estimateStrength(pw, m.inpService.Value(), m.inpUser.Value())
+	style := lipgloss.NewStyle().Foreground(scoreColors[s.Score])
+
+	meter := strings.Repeat("█", 2*s.Score) + strings.Repeat("░", STRENGTH_METER_WIDTH-2*s.Score)
+	render := style.Render(meter+" "+scoreNames[s.Score]) + " · " + formatGuesses(s.Guesses) + " guesses · " +
+		formatCrackTime(s.CrackTimes[CRACK_OFFLINE_SLOW]) + " to crack offline"
+
+	if len(s.Warning) > 0 {
+		render += "\n" + style.Render(s.Warning)
+	}
+
+	return render + "\n"
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var trashRestoredAdditive = baseStyle.Foreground(lipgloss.Color("127")).Render("Entry restored!") + "\n"
+
+// The state of the trash screen which lists the deleted accounts of the active vault
+// (see "trash.go") and allows to restore or to delete them permanently.
+type trashView struct {
+	active bool
+	table  table.Model
+	// The deleted accounts, most recently deleted first
+	trash []trashedAccount
+	// Whether the selected account is about to be deleted permanently (see "trashKeyMap.Delete")
+	confirm bool
+	err     error
+	KeyMap  trashKeyMap
+}
+
+type trashKeyMap struct {
+	Restore key.Binding
+	Delete  key.Binding
+	Back    key.Binding
+	Quit    key.Binding
+}
+
+// Returns the columns of the trash table for the given width of the terminal window.
+func trashColumns(width int) []table.Column {
+	return []table.Column{
+		{Title: "Deleted at", Width: 20},
+		{Title: "Service", Width: max(width/5, 12)},
+		{Title: "User", Width: max(width/5, 12)},
+		{Title: "Purged at", Width: 20},
+	}
+}
+
+// Adapts the size of the trash table to the size of the terminal window.
+func (tv *trashView) resize(width int, height int) {
+	tv.table.SetColumns(trashColumns(width))
+	tv.table.SetHeight(height / 3 * 2)
+}
+
+// Reads the trash of the active vault into the trash table.
+func (tv *trashView) reload() error {
+	trash, err := readTrash()
+	if err != nil {
+		return err
+	}
+
+	slices.Reverse(trash)
+
+	rows := []table.Row{}
+
+	for _, t := range trash {
+		purged := "Never"
+
+		if expires := t.expires(); !expires.IsZero() {
+			purged = expires.Local().Format(time.DateTime)
+		}
+
+		rows = append(rows, table.Row{
+			t.Deleted.Local().Format(time.DateTime),
+			t.Account.Service,
+			t.Account.User,
+			purged,
+		})
+	}
+
+	tv.trash = trash
+	tv.table.SetRows(rows)
+
+	if tv.table.Cursor() >= len(rows) {
+		tv.table.SetCursor(len(rows) - 1)
+	}
+
+	return nil
+}
+
+// Opens the trash of the active vault.
+func (m model) startTrash() (tea.Model, tea.Cmd) {
+	t := table.New(
+		table.WithColumns(trashColumns(m.width)),
+		table.WithFocused(true),
+	)
+	t.SetStyles(m.tableStyles)
+
+	m.trash = trashView{
+		active: true,
+		table:  t,
+		KeyMap: trashKeyMap{
+			Restore: key.NewBinding(
+				key.WithKeys("enter", "ctrl+s"),
+				key.WithHelp("enter/ctrl+s", "Restore"),
+			),
+			Delete: key.NewBinding(
+				key.WithKeys("ctrl+d"),
+				key.WithHelp("ctrl+d", "Delete permanently"),
+			),
+			Back: key.NewBinding(
+				key.WithKeys("esc", "shift+tab"),
+				key.WithHelp("esc/shift+tab", "Back"),
+			),
+			Quit: key.NewBinding(
+				key.WithKeys("ctrl+c"),
+				key.WithHelp("ctrl+c", "Quit"),
+			),
+		},
+	}
+
+	if err := m.trash.reload(); err != nil {
+		m.trash = trashView{}
+		showAdditive(errorStyle.Render("Could not read the trash: "+err.Error())+"\n", 10*time.Second)
+
+		return m, nil
+	}
+
+	m.trash.resize(m.width, m.height)
+	m.table.Blur()
+
+	return m, tea.ClearScreen
+}
+
+// Restores the selected account of the trash into the vault. The account is added to the vault
+// before it is removed from the trash, so that it is never lost.
+func (m *model) restoreFromTrash() error {
+	a := m.trash.trash[m.trash.table.Cursor()].Account
+
+	// The account may have been restored already by undoing its deletion
+	if accountIndex(*m.accounts, a.ID) < 0 {
+		*m.accounts = append(*m.accounts, a)
+		m.recordUndo(nil, &a, len(*m.accounts)-1)
+
+		temp := (*m.accounts)[1:]
+		if err := saveAndJournal(&temp, newJournalEntry(nil, &a, "Restored from the trash")); err != nil {
+			return err
+		}
+
+		m.refreshRows()
+	}
+
+	_, err := untrashAccount(a.ID)
+
+	return err
+}
+
+// Deletes the selected account of the trash permanently.
+func (m *model) deleteFromTrash() error {
+	a, err := untrashAccount(m.trash.trash[m.trash.table.Cursor()].Account.ID)
+	if err != nil {
+		return err
+	}
+
+	return appendJournal(newJournalEntry(&a, nil, "Deleted permanently from the trash"))
+}
+
+// Handles messages while the trash screen is active.
+func (m model) updateTrash(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		// Any other key cancels the permanent deletion
+		if m.trash.confirm && !key.Matches(msg, m.trash.KeyMap.Delete) {
+			m.trash.confirm = false
+			return m, nil
+		}
+
+		switch {
+
+		case key.Matches(msg, m.trash.KeyMap.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, m.trash.KeyMap.Back):
+			m.trash = trashView{}
+			m.table.Focus()
+
+			return m, tea.ClearScreen
+
+		case key.Matches(msg, m.trash.KeyMap.Restore):
+			if len(m.trash.trash) == 0 || m.readOnly() {
+				break
+			}
+
+			m.trash.err = m.restoreFromTrash()
+
+			if m.trash.err == nil {
+				showAdditive(trashRestoredAdditive, 3*time.Second)
+			}
+
+			if err := m.trash.reload(); err != nil {
+				m.trash.err = err
+			}
+
+			return m, nil
+
+		case key.Matches(msg, m.trash.KeyMap.Delete):
+			if len(m.trash.trash) == 0 || m.readOnly() {
+				break
+			}
+
+			if !m.trash.confirm {
+				m.trash.confirm = true
+				return m, nil
+			}
+
+			m.trash.confirm = false
+			m.trash.err = m.deleteFromTrash()
+
+			if err := m.trash.reload(); err != nil {
+				m.trash.err = err
+			}
+
+			return m, nil
+		}
+	}
+
+	m.trash.table, cmd = m.trash.table.Update(msg)
+
+	return m, cmd
+}
+
+func (m model) viewTrash() string {
+	var content string
+
+	if len(m.trash.trash) == 0 {
+		content = "The trash is empty. Deleted entries are kept here until they are purged."
+	} else {
+		content = "Trash\n\n" + m.trash.table.View()
+	}
+
+	finalRender := baseStyle.Render(content) + "\n"
+	finalRender += baseStyle.Render(m.Help.ShortHelpView([]key.Binding{
+		m.trash.KeyMap.Restore, m.trash.KeyMap.Delete, m.trash.KeyMap.Back, m.trash.KeyMap.Quit,
+	})) + "\n"
+
+	if m.trash.confirm {
+		service := m.trash.trash[m.trash.table.Cursor()].Account.Service
+		finalRender += errorStyle.Render("Delete "+service+" permanently? Press ctrl+d again to confirm, any other key to cancel.") + "\n"
+	}
+
+	if m.trash.err != nil {
+		finalRender += errorStyle.Render(m.trash.err.Error()) + "\n"
+	}
+
+	if pwCopied {
+		finalRender += pwAdditive
+	}
+
+	return finalRender
+}
+
+// Renders the confirmation shown after the delete key has been pressed once (see "customSelKeyMap.Delete").
+func (m model) viewConfirmDelete() string {
+	if !m.confirmDelete {
+		return ""
+	}
+
+	index := m.selectedIndex()
+
+	return errorStyle.Render("Move "+(*m.accounts)[index].Service+" to the trash? Press ctrl+d again to confirm, any other key to cancel.") + "\n"
+}
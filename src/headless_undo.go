@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The maximum number of changes which can be undone within a session.
+const UNDO_LIMIT = 100
+
+// A change of a single account made within the TUI which can be undone. Only the changes of
+// the active vault are kept, they are discarded once another vault is opened.
+type undoStep struct {
+	// The account before the change, nil if it has been added
+	before *account
+	// The account after the change, nil if it has been deleted
+	after *account
+	// The index of the account within the accounts (used to restore deleted accounts at their position)
+	index int
+}
+
+// Returns a short description of the given change, e.g. "Deleted github".
+func (s undoStep) String() string {
+	switch {
+
+	case s.before == nil:
+		return "Added " + s.after.Service
+
+	case s.after == nil:
+		return "Deleted " + s.before.Service
+	}
+
+	return "Changed " + s.after.Service
+}
+
+// Remembers the given change so that it can be undone. Changes which have been undone
+// can no longer be redone afterwards.
+func (m *model) recordUndo(before *account, after *account, index int) {
+	m.undo = append(m.undo, undoStep{before: before, after: after, index: index})
+	m.undo = m.undo[max(len(m.undo)-UNDO_LIMIT, 0):]
+	m.redo = nil
+}
+
+// Discards all changes which could be undone or redone, e.g. after another vault has been opened.
+func (m *model) clearUndo() {
+	m.undo = nil
+	m.redo = nil
+}
+
+// Replaces the account from with the account to within the given accounts. from is nil if to
+// has to be added (at the given index), to is nil if from has to be deleted. The time the
+// account has last been used is kept and if the password is replaced, the current password is
+// added to the history like any other change (see "recordPwChange()"), so that undoing a change
+// never loses a password. Does nothing if from no longer exists.
+func applyChange(accounts *[]account, from *account, to *account, index int) {
+	if from == nil {
+		*accounts = slices.Insert(*accounts, min(index, len(*accounts)), *to)
+		return
+	}
+
+	current := accountIndex(*accounts, from.ID)
+	if current < 1 {
+		return
+	}
+
+	if to == nil {
+		*accounts = slices.Delete(*accounts, current, current+1)
+	} else {
+		replacement := *to
+		replacement.LastUsed = (*accounts)[current].LastUsed
+		replacement.History = (*accounts)[current].History
+		recordPwChange((*accounts)[current], &replacement)
+
+		(*accounts)[current] = replacement
+	}
+}
+
+// Undoes the most recent change if undo is true, otherwise redoes the most recently undone change.
+// The vault is saved right away and the change is recorded in the journal.
+func (m model) replayChange(undo bool) (tea.Model, tea.Cmd) {
+	from, to, note := &m.undo, &m.redo, "Undo"
+	if !undo {
+		from, to, note = &m.redo, &m.undo, "Redo"
+	}
+
+	if len(*from) == 0 || m.readOnly() {
+		return m, nil
+	}
+
+	step := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+
+	before, after := step.before, step.after
+	if undo {
+		before, after = step.after, step.before
+	}
+
+	if before != nil && accountIndex(*m.accounts, before.ID) < 1 {
+		showAdditive(errorStyle.Render(fmt.Sprintf("%s failed: the entry %q no longer exists", note, before.Service))+"\n", 10*time.Second)
+		return m, nil
+	}
+
+	// The account may have been restored from the trash in the meantime
+	if before == nil && accountIndex(*m.accounts, after.ID) >= 1 {
+		showAdditive(errorStyle.Render(fmt.Sprintf("%s failed: the entry %q already exists", note, after.Service))+"\n", 10*time.Second)
+		return m, nil
+	}
+
+	// Deleted accounts are moved to the trash before they are removed from the vault and restored
+	// accounts are removed from the trash after they have been saved (see "trash.go")
+	if after == nil {
+		if err := trashAccount(*before); err != nil {
+			showAdditive(errorStyle.Render(fmt.Sprintf("%s failed: %v", note, err))+"\n", 10*time.Second)
+			*from = append(*from, step)
+
+			return m, nil
+		}
+	}
+
+	applyChange(m.accounts, before, after, step.index)
+	*to = append(*to, step)
+
+	temp := (*m.accounts)[1:]
+	err := saveAndJournal(&temp, newJournalEntry(before, after, note))
+
+	if err != nil {
+		showAdditive(saveErrorAdditive(err), 10*time.Second)
+	} else {
+		showAdditive(baseStyle.Foreground(lipgloss.Color("127")).Render(note+": "+step.String())+"\n", 3*time.Second)
+	}
+
+	if before == nil && (err == nil || errors.Is(err, ErrJournal)) {
+		if _, err := untrashAccount(after.ID); err != nil && !errors.Is(err, ErrNotFound) {
+			showAdditive(errorStyle.Render("Could not remove the entry from the trash: "+err.Error())+"\n", 10*time.Second)
+		}
+	}
+
+	m.refreshRows()
+
+	return m, nil
+}
@@ -0,0 +1,610 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The modes of the vault picker.
+const (
+	// Picking the vault to open
+	VAULTS_OPEN = iota
+	// Picking the vault to move the selected account to
+	VAULTS_MOVE
+)
+
+// The actions of the vault picker which require some input from the user.
+const (
+	VAULT_ACTION_NONE = iota
+	VAULT_ACTION_CREATE
+	VAULT_ACTION_RENAME
+	VAULT_ACTION_DELETE
+	VAULT_ACTION_UNLOCK
+	VAULT_ACTION_CHOOSE
+	VAULT_ACTION_CONFIRM
+	VAULT_ACTION_UNLOCK_TARGET
+)
+
+// The state of the vault picker which lists all named vaults. It is shown before the
+// accounts table and allows to open, create, rename and delete vaults as well as to
+// pick the vault an account is moved to.
+type vaultsView struct {
+	active bool
+	mode   int
+	action int
+	table  table.Model
+	names  []string
+	input  textinput.Model
+	prompt pwPromptModel
+	// The path of the vault being opened or the name of the vault an account is moved to
+	target string
+	newPw  []byte
+	err    error
+	KeyMap vaultsKeyMap
+}
+
+type vaultsKeyMap struct {
+	Open   key.Binding
+	New    key.Binding
+	Rename key.Binding
+	Delete key.Binding
+	Back   key.Binding
+	Quit   key.Binding
+}
+
+// Returns the key bindings shown in the help of the vault picker.
+func (vv vaultsView) helpBindings() []key.Binding {
+	if vv.action != VAULT_ACTION_NONE {
+		return []key.Binding{promptSubmit, promptCancel}
+	}
+
+	if vv.mode == VAULTS_MOVE {
+		return []key.Binding{vv.KeyMap.Open, vv.KeyMap.Back, vv.KeyMap.Quit}
+	}
+
+	return []key.Binding{vv.KeyMap.Open, vv.KeyMap.New, vv.KeyMap.Rename, vv.KeyMap.Delete, vv.KeyMap.Back, vv.KeyMap.Quit}
+}
+
+// Returns the columns of the vaults table for the given width of the terminal window.
+func vaultsColumns(width int) []table.Column {
+	return []table.Column{
+		{Title: "Vault", Width: max(width/3, 20)},
+		{Title: "Status", Width: 10},
+	}
+}
+
+// Adapts the size of the vaults table to the size of the terminal window.
+func (vv *vaultsView) resize(width int, height int) {
+	vv.table.SetColumns(vaultsColumns(width))
+	vv.table.SetHeight(height / 3 * 2)
+}
+
+// Returns the vault name selected within the vaults table.
+func (vv vaultsView) selectedName() (string, bool) {
+	if len(vv.names) == 0 {
+		return "", false
+	}
+
+	return vv.names[vv.table.Cursor()], true
+}
+
+// Lists the vaults and updates the rows of the vaults table. In VAULTS_OPEN mode, the
+// active vault is always listed (even if it does not exist yet) and selected.
+func (m *model) refreshVaults() {
+	names, err := listVaults()
+	if err != nil {
+		m.vaults.err = err
+		names = []string{}
+	}
+
+	if m.vaults.mode == VAULTS_MOVE {
+		names = slices.DeleteFunc(names, func(name string) bool {
+			return namedVaultPath(name) == vaultPath
+		})
+	} else if !slices.Contains(names, vaultName()) && vaultNamePattern.MatchString(vaultName()) {
+		names = append(names, vaultName())
+		slices.Sort(names)
+	}
+
+	rows := []table.Row{}
+	cursor := 0
+
+	for i, name := range names {
+		status := ""
+
+		if _, err := os.Stat(namedVaultPath(name)); os.IsNotExist(err) {
+			status = "new"
+		} else if m.loaded && namedVaultPath(name) == vaultPath {
+			status = "open"
+		}
+
+		if namedVaultPath(name) == vaultPath {
+			cursor = i
+		}
+
+		rows = append(rows, table.Row{name, status})
+	}
+
+	m.vaults.names = names
+	m.vaults.table.SetRows(rows)
+	m.vaults.table.SetCursor(cursor)
+}
+
+// Opens the vault picker in the given mode.
+func (m model) startVaults(mode int) (model, tea.Cmd) {
+	t := table.New(
+		table.WithColumns(vaultsColumns(m.width)),
+		table.WithFocused(true),
+	)
+	t.SetStyles(m.tableStyles)
+
+	m.vaults = vaultsView{
+		active: true,
+		mode:   mode,
+		table:  t,
+		KeyMap: vaultsKeyMap{
+			Open: key.NewBinding(
+				key.WithKeys("enter", "tab"),
+				key.WithHelp("enter/tab", "Select"),
+			),
+			New: key.NewBinding(
+				key.WithKeys("ctrl+n"),
+				key.WithHelp("ctrl+n", "New vault"),
+			),
+			Rename: key.NewBinding(
+				key.WithKeys("ctrl+r"),
+				key.WithHelp("ctrl+r", "Rename"),
+			),
+			Delete: key.NewBinding(
+				key.WithKeys("ctrl+d"),
+				key.WithHelp("ctrl+d", "Delete"),
+			),
+			Back: key.NewBinding(
+				key.WithKeys("esc", "shift+tab"),
+				key.WithHelp("esc/shift+tab", "Back"),
+			),
+			Quit: key.NewBinding(
+				key.WithKeys("ctrl+c"),
+				key.WithHelp("ctrl+c", "Quit"),
+			),
+		},
+	}
+
+	m.vaults.resize(m.width, m.height)
+	m.refreshVaults()
+	m.table.Blur()
+
+	return m, tea.ClearScreen
+}
+
+// Closes the vault picker and returns to the accounts table or the edit screen.
+func (m model) stopVaults() (tea.Model, tea.Cmd) {
+	zero(&m.vaults.newPw)
+	m.vaults = vaultsView{}
+
+	if m.selected == nil {
+		m.table.Focus()
+	}
+
+	return m, tea.ClearScreen
+}
+
+// Starts an action of the vault picker which requires the user to enter some text.
+func (m model) startVaultInput(action int, placeholder string, value string) (tea.Model, tea.Cmd) {
+	ti := textinput.New()
+	ti.Placeholder = placeholder
+	ti.Width = 64
+	ti.PromptStyle = focusedStyle
+	ti.SetValue(value)
+
+	m.vaults.action = action
+	m.vaults.input = ti
+	m.vaults.err = nil
+
+	return m, m.vaults.input.Focus()
+}
+
+// Starts an action of the vault picker which requires the user to enter a password.
+func (m model) startVaultPrompt(action int, title string, hint string) (tea.Model, tea.Cmd) {
+	m.vaults.action = action
+	m.vaults.prompt = newPwPrompt(title, hint)
+
+	return m, m.vaults.prompt.Init()
+}
+
+// Opens the vault at the given path. If the vault requires a master password, the user is
+// asked to enter it (or to choose one if the vault does not exist yet) before it is loaded.
+func (m model) openVault(path string) (tea.Model, tea.Cmd) {
+	if m.loaded && path == vaultPath {
+		return m.stopVaults()
+	}
+
+	selectVault(path)
+
+	m.loaded = false
+	m.vaults.target = path
+
+	encryptedData, err := readFile(path)
+	if err != nil {
+		return m.showError(err)
+	}
+
+	exists, required := len(encryptedData) > 0, needsMasterPw(encryptedData)
+	zero(&encryptedData)
+
+	name := filepath.Base(path)
+
+	if !required {
+		return m.loadVault()
+	} else if exists {
+		return m.startVaultPrompt(VAULT_ACTION_UNLOCK, fmt.Sprintf("Enter the master password of the vault %q", name), "")
+	}
+
+	return m.startVaultPrompt(VAULT_ACTION_CHOOSE, fmt.Sprintf("Choose a master password for the new vault %q", name), "")
+}
+
+// Loads the accounts of the active vault into the accounts table and closes the vault picker.
+// New vaults are written to disk right away so that they show up in the vault picker.
+func (m model) loadVault() (tea.Model, tea.Cmd) {
+	accounts, err := getAllAccounts()
+	m.integrityErr = nil
+
+	// Undecodable accounts are kept, but the vault is opened read-only so they are never overwritten
+	if errors.Is(err, ErrUndecodable) && len(*accounts) > 0 {
+		m.integrityErr, err = err, nil
+	}
+
+	if err != nil {
+		if m.vaults.action == VAULT_ACTION_UNLOCK && errors.Is(err, ErrWrongKey) {
+			return m.startVaultPrompt(VAULT_ACTION_UNLOCK, m.vaults.prompt.title, "Wrong master password!")
+		}
+
+		// The vault can't be opened at all (e.g. because it is corrupt)
+		return m.showError(err)
+	}
+
+	if _, err := os.Stat(vaultPath); os.IsNotExist(err) {
+		if err := saveAccountsToDisk(accounts); err != nil {
+			m.vaults.action = VAULT_ACTION_NONE
+			m.vaults.err = err
+
+			return m, nil
+		}
+	}
+
+	*m.accounts = append((*m.accounts)[:1], *accounts...)
+
+	if err := loadAuditCache(); err != nil {
+		showAdditive(errorStyle.Render("Could not read the results of the breach check: "+err.Error())+"\n", 10*time.Second)
+	}
+
+	m.search.SetValue("")
+	m.refreshRows()
+	m.clearUndo()
+
+	if m.integrityErr == nil {
+		if err := purgeTrash(); err != nil {
+			showAdditive(errorStyle.Render("Could not purge the trash: "+err.Error())+"\n", 10*time.Second)
+		}
+	}
+	m.table.GotoTop()
+	m.loaded = true
+
+	rekeyable = len(masterPw) > 0
+	m.KeyMap.Rekey.SetEnabled(rekeyable && !blurred)
+
+	return m.stopVaults()
+}
+
+// Moves the selected account to the vault with the given name using the given master password.
+// If the master password is empty or wrong, the user is asked to enter the master password
+// of the target vault.
+func (m model) moveAccount(name string, pw []byte) (tea.Model, tea.Cmd) {
+	m.vaults.target = name
+
+	encryptedData, err := readFile(namedVaultPath(name))
+	if err != nil {
+		m.vaults.action = VAULT_ACTION_NONE
+		m.vaults.err = err
+
+		return m, nil
+	}
+
+	required := requiresMasterPw(encryptedData)
+	zero(&encryptedData)
+
+	if required && len(pw) == 0 {
+		return m.startVaultPrompt(VAULT_ACTION_UNLOCK_TARGET, fmt.Sprintf("Enter the master password of the vault %q", name), "")
+	}
+
+	index := m.selectedIndex()
+
+	// The account has been moved even if only the journal of the target vault could not be written
+	if err := appendToVault(name, pw, (*m.accounts)[index]); err != nil && !errors.Is(err, ErrJournal) {
+		if required && errors.Is(err, ErrWrongKey) {
+			hint := ""
+
+			// The master password of the active vault is tried first without bothering the user
+			if m.vaults.action == VAULT_ACTION_UNLOCK_TARGET {
+				hint = "Wrong master password!"
+			}
+
+			return m.startVaultPrompt(VAULT_ACTION_UNLOCK_TARGET, fmt.Sprintf("Enter the master password of the vault %q", name), hint)
+		}
+
+		m.vaults.action = VAULT_ACTION_NONE
+		m.vaults.err = err
+
+		return m, nil
+	}
+
+	moved := (*m.accounts)[index]
+	*m.accounts = slices.Delete(*m.accounts, index, index+1)
+
+	temp := (*m.accounts)[1:]
+	if err := saveAndJournal(&temp, newJournalEntry(&moved, nil, fmt.Sprintf("Moved to vault %s", name))); err != nil {
+		showAdditive(saveErrorAdditive(err), 10*time.Second)
+	} else {
+		showAdditive(baseStyle.Foreground(lipgloss.Color("127")).Render(fmt.Sprintf("Moved to %q!", name))+"\n", 3*time.Second)
+	}
+
+	m.inpService.Blur()
+	m.inpDescription.Blur()
+	m.inpNotes.Blur()
+	m.inpUser.Blur()
+	m.inpPw.Blur()
+
+	m.focus = 0
+	m.selected = nil
+
+	m.refreshRows()
+
+	return m.stopVaults()
+}
+
+// Performs the action of the vault picker using the text entered by the user.
+func (m model) submitVaultInput() (tea.Model, tea.Cmd) {
+	value := m.vaults.input.Value()
+	selected, _ := m.vaults.selectedName()
+
+	var err error
+
+	switch m.vaults.action {
+
+	case VAULT_ACTION_CREATE:
+		if err = checkVaultName(value); err != nil {
+			break
+		}
+
+		if _, statErr := os.Stat(namedVaultPath(value)); statErr == nil {
+			err = fmt.Errorf("A vault named %q already exists", value)
+			break
+		}
+
+		m.vaults.action = VAULT_ACTION_NONE
+
+		return m.openVault(namedVaultPath(value))
+
+	case VAULT_ACTION_RENAME:
+		err = renameVault(selected, value)
+
+	case VAULT_ACTION_DELETE:
+		if value != selected {
+			err = fmt.Errorf("The entered name does not match %q", selected)
+			break
+		}
+
+		if namedVaultPath(selected) == vaultPath {
+			zero(&masterPw)
+			masterPw = nil
+
+			*m.accounts = (*m.accounts)[:1]
+			m.refreshRows()
+			m.loaded = false
+		}
+
+		err = deleteVault(selected)
+	}
+
+	if err != nil {
+		m.vaults.err = err
+		return m, nil
+	}
+
+	m.vaults.action = VAULT_ACTION_NONE
+	m.refreshVaults()
+
+	return m, nil
+}
+
+// Handles the password entered by the user for the action of the vault picker.
+func (m model) submitVaultPrompt() (tea.Model, tea.Cmd) {
+	pw := []byte(m.vaults.prompt.input.Value())
+	name := filepath.Base(m.vaults.target)
+
+	switch m.vaults.action {
+
+	case VAULT_ACTION_UNLOCK:
+		masterPw = pw
+		return m.loadVault()
+
+	case VAULT_ACTION_CHOOSE:
+		m.vaults.newPw = pw
+		return m.startVaultPrompt(VAULT_ACTION_CONFIRM, "Confirm your master password", "")
+
+	case VAULT_ACTION_CONFIRM:
+		if !slices.Equal(pw, m.vaults.newPw) {
+			zero(&pw)
+			zero(&m.vaults.newPw)
+
+			m.vaults.newPw = nil
+
+			return m.startVaultPrompt(VAULT_ACTION_CHOOSE, fmt.Sprintf("Choose a master password for the new vault %q", name), "The passwords did not match!")
+		}
+
+		zero(&pw)
+
+		masterPw = m.vaults.newPw
+		m.vaults.newPw = nil
+
+		return m.loadVault()
+
+	case VAULT_ACTION_UNLOCK_TARGET:
+		defer zero(&pw)
+		return m.moveAccount(m.vaults.target, pw)
+	}
+
+	return m, nil
+}
+
+// Handles messages while the vault picker is active.
+func (m model) updateVaults(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch m.vaults.action {
+
+	case VAULT_ACTION_CREATE, VAULT_ACTION_RENAME, VAULT_ACTION_DELETE:
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch {
+
+			case key.Matches(msg, promptCancel):
+				m.vaults.action = VAULT_ACTION_NONE
+				m.vaults.err = nil
+
+				return m, nil
+
+			case key.Matches(msg, promptSubmit):
+				return m.submitVaultInput()
+			}
+		}
+
+		m.vaults.input, cmd = m.vaults.input.Update(msg)
+
+		return m, cmd
+
+	case VAULT_ACTION_UNLOCK, VAULT_ACTION_CHOOSE, VAULT_ACTION_CONFIRM, VAULT_ACTION_UNLOCK_TARGET:
+		res, cmd := m.vaults.prompt.Update(msg)
+		m.vaults.prompt = res.(pwPromptModel)
+
+		if m.vaults.prompt.cancelled {
+			zero(&m.vaults.newPw)
+
+			m.vaults.newPw = nil
+			m.vaults.action = VAULT_ACTION_NONE
+
+			m.refreshVaults()
+
+			return m, nil
+		}
+
+		if m.vaults.prompt.submitted {
+			return m.submitVaultPrompt()
+		}
+
+		return m, cmd
+	}
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		selected, found := m.vaults.selectedName()
+
+		switch {
+
+		case key.Matches(msg, m.vaults.KeyMap.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, m.vaults.KeyMap.Back):
+			if m.vaults.mode == VAULTS_MOVE || m.loaded {
+				return m.stopVaults()
+			}
+
+		case key.Matches(msg, m.vaults.KeyMap.Open):
+			if !found {
+				break
+			}
+
+			if m.vaults.mode == VAULTS_MOVE {
+				return m.moveAccount(selected, masterPw)
+			}
+
+			return m.openVault(namedVaultPath(selected))
+
+		case key.Matches(msg, m.vaults.KeyMap.New):
+			if m.vaults.mode == VAULTS_OPEN {
+				return m.startVaultInput(VAULT_ACTION_CREATE, "Name of the new vault", "")
+			}
+
+		case key.Matches(msg, m.vaults.KeyMap.Rename):
+			if m.vaults.mode == VAULTS_OPEN && found {
+				return m.startVaultInput(VAULT_ACTION_RENAME, "New name of the vault", selected)
+			}
+
+		case key.Matches(msg, m.vaults.KeyMap.Delete):
+			if m.vaults.mode == VAULTS_OPEN && found {
+				return m.startVaultInput(VAULT_ACTION_DELETE, "Type the name of the vault to confirm", "")
+			}
+		}
+	}
+
+	m.vaults.table, cmd = m.vaults.table.Update(msg)
+
+	return m, cmd
+}
+
+func (m model) viewVaults() string {
+	var content string
+	selected, _ := m.vaults.selectedName()
+
+	switch m.vaults.action {
+
+	case VAULT_ACTION_UNLOCK, VAULT_ACTION_CHOOSE, VAULT_ACTION_CONFIRM, VAULT_ACTION_UNLOCK_TARGET:
+		return m.vaults.prompt.View()
+
+	case VAULT_ACTION_CREATE:
+		content = "Create a new vault\n\n" + m.vaults.input.View()
+
+	case VAULT_ACTION_RENAME:
+		content = fmt.Sprintf("Rename the vault %q\n\n%s", selected, m.vaults.input.View())
+
+	case VAULT_ACTION_DELETE:
+		content = fmt.Sprintf(
+			"Delete the vault %q including all of its backups? This can't be undone!\n\n%s",
+			selected, m.vaults.input.View(),
+		)
+
+	default:
+		if m.vaults.mode == VAULTS_MOVE {
+			content = "Move the entry to another vault\n\n"
+		} else {
+			content = "Vaults in " + vaultsDir() + "\n\n"
+		}
+
+		if len(m.vaults.names) == 0 && m.vaults.mode == VAULTS_MOVE {
+			content += "There are no other vaults yet. Create one using the vault picker first."
+		} else {
+			content += m.vaults.table.View()
+		}
+	}
+
+	finalRender := baseStyle.Render(content) + "\n"
+	finalRender += baseStyle.Render(m.Help.ShortHelpView(m.vaults.helpBindings())) + "\n"
+
+	if m.vaults.err != nil {
+		finalRender += errorStyle.Render(m.vaults.err.Error()) + "\n"
+	}
+
+	if pwCopied {
+		finalRender += pwAdditive
+	}
+
+	return finalRender
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+)
+
+// The maximum number of changes kept in the journal of a vault. Older changes are dropped.
+const JOURNAL_LENGTH = 1000
+
+// The kinds of changes recorded in the journal.
+const (
+	JOURNAL_ADDED   = "added"
+	JOURNAL_CHANGED = "changed"
+	JOURNAL_DELETED = "deleted"
+)
+
+// A change of an entry recorded in the journal. Only the names of the changed fields are
+// recorded, never their values (previous passwords are kept in "account.History" instead).
+type journalEntry struct {
+	Time time.Time `json:"time"`
+	// The user and host which made the change (see "journalAuthor()")
+	Author  string   `json:"author"`
+	Action  string   `json:"action"`
+	ID      string   `json:"id"`
+	Service string   `json:"service"`
+	User    string   `json:"user"`
+	Fields  []string `json:"fields,omitempty"`
+	// Describes how the change has been made (e.g. "Undo" or "Moved to vault team"), if not by editing the entry
+	Note string `json:"note,omitempty"`
+}
+
+// Returns the path of the journal of the active vault. It is located next to the vault and
+// encrypted using the same key.
+func journalPath() string {
+	return vaultPath + ".journal"
+}
+
+// Returns the name of the current user and host in the form user@host.
+func journalAuthor() string {
+	name := "unknown"
+
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	if host, err := os.Hostname(); err == nil {
+		name += "@" + host
+	}
+
+	return name
+}
+
+// Returns the names of the fields whose values differ between the given accounts.
+func changedFields(a account, b account) []string {
+	fields := []string{}
+
+	for _, f := range []struct {
+		name string
+		a, b string
+	}{
+		{"service", a.Service, b.Service},
+		{"description", a.Description, b.Description},
+		{"notes", a.Notes, b.Notes},
+		{"user", a.User, b.User},
+		{"pw", a.Pw, b.Pw},
+	} {
+		if f.a != f.b {
+			fields = append(fields, f.name)
+		}
+	}
+
+	return fields
+}
+
+// Returns the details of the given journal entry shown in the journal table and the table
+// output of the journal command, i.e. the changed fields and how the change has been made.
+func journalDetails(e journalEntry) string {
+	details := []string{}
+
+	if len(e.Fields) > 0 {
+		details = append(details, strings.Join(e.Fields, ", "))
+	}
+
+	if len(e.Note) > 0 {
+		details = append(details, e.Note)
+	}
+
+	return strings.Join(details, " · ")
+}
+
+// Returns the journal entry describing the change from before to after. before is nil if the
+// account has been added, after is nil if it has been deleted.
+func newJournalEntry(before *account, after *account, note string) journalEntry {
+	e := journalEntry{
+		Time:   now(),
+		Author: journalAuthor(),
+		Note:   note,
+	}
+
+	switch {
+
+	case before == nil:
+		e.Action = JOURNAL_ADDED
+		e.ID, e.Service, e.User = after.ID, after.Service, after.User
+
+	case after == nil:
+		e.Action = JOURNAL_DELETED
+		e.ID, e.Service, e.User = before.ID, before.Service, before.User
+
+	default:
+		e.Action = JOURNAL_CHANGED
+		e.ID, e.Service, e.User = after.ID, after.Service, after.User
+		e.Fields = changedFields(*before, *after)
+	}
+
+	return e
+}
+
+// Returns the journal entries describing the changes from the accounts before to the accounts
+// after, e.g. after a snapshot has been restored. Accounts are identified by their ID.
+func journalDiff(before []account, after []account, note string) []journalEntry {
+	entries := []journalEntry{}
+
+	for i := range after {
+		if index := accountIndex(before, after[i].ID); index < 0 {
+			entries = append(entries, newJournalEntry(nil, &after[i], note))
+		} else if !sameContent(before[index], after[i]) {
+			entries = append(entries, newJournalEntry(&before[index], &after[i], note))
+		}
+	}
+
+	for i := range before {
+		if accountIndex(after, before[i].ID) < 0 {
+			entries = append(entries, newJournalEntry(&before[i], nil, note))
+		}
+	}
+
+	return entries
+}
+
+// Decrypts the journal of the active vault and returns its entries, oldest first.
+// A journal which doesn't exist is treated as an empty journal.
+func readJournal() ([]journalEntry, error) {
+	entries := []journalEntry{}
+
+	if err := readEncryptedJSON(journalPath(), &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Encrypts the given journal entries and writes them to the journal of the active vault.
+// Only the most recent entries are kept (see "JOURNAL_LENGTH").
+func writeJournal(entries []journalEntry) error {
+	return writeEncryptedJSON(journalPath(), entries[max(len(entries)-JOURNAL_LENGTH, 0):])
+}
+
+// Appends the given entries to the journal of the active vault. Does nothing if there are no entries.
+func appendJournal(entries ...journalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	journal, err := readJournal()
+	if err != nil {
+		return err
+	}
+
+	return writeJournal(append(journal, entries...))
+}
+
+// Saves the accounts (see "saveAccountsToDisk()") and appends the given entries to the journal.
+// If only the journal could not be written, the returned error wraps ErrJournal.
+func saveAndJournal(accounts *[]account, entries ...journalEntry) error {
+	if err := saveAccountsToDisk(accounts); err != nil {
+		return err
+	}
+
+	if err := appendJournal(entries...); err != nil {
+		return fmt.Errorf("%w: %w", ErrJournal, err)
+	}
+
+	return nil
+}
@@ -3,92 +3,103 @@
 package main
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
 	"crypto/sha256"
-	"errors"
+	"flag"
+	"fmt"
 )
 
-// Returns the SHA-256 hash of the data stored in /etc/machine-id.
-func linuxGetKey() []byte {
-	mId := readFile("/etc/machine-id")
+// On Linux, the native backend is the portable backend. Therefore, the accounts file
+// is always protected by a key derived from the master password.
+const nativeUsesMasterPw = true
+
+// The path to the file containing the machine ID which was used by older versions of PwdMan
+// to derive the key on Linux. Can be changed using the -machine-id flag in order to migrate
+// legacy vaults after the machine ID has changed (e.g. after a reinstallation).
+var machineIdPath = "/etc/machine-id"
+
+// The -machine-id flag only exists on Linux, as legacy vaults on Windows are protected by the DPAPI.
+func init() {
+	flag.StringVar(&machineIdPath, "machine-id", machineIdPath, "The file containing the machine ID which protects a legacy vault. Use a copy of the old /etc/machine-id to migrate a vault after the machine ID has changed.")
+}
+
+// Returns the SHA-256 hash of the data stored in /etc/machine-id (see "machineIdPath").
+// This key was used by older versions of PwdMan and is only used to read
+// accounts files that have not been re-encrypted with a master password yet.
+func linuxGetLegacyKey() ([]byte, error) {
+	mId, err := readFile(machineIdPath)
+	if err != nil {
+		return nil, err
+	}
 
 	h := sha256.New()
 	h.Write(mId)
 
 	zero(&mId)
 
-	return h.Sum(nil)
+	return h.Sum(nil), nil
 }
 
-// Encrypts some data and returns a pointer pointing to the encrypted byte slice.
+// Encrypts some data using the native backend and returns a pointer pointing to the
+// encrypted byte slice which is prefixed with a vault header (see "VAULT_VERSION").
 // Also zeros the given data.
 // Note: On Windows, this function will benefit from Windows's [Data Protection API (DPAPI)]
-// by using [billgraziano]'s [Go wrapper]. On Linux, this function will utilize the
-// symmetric [AES-GCM] cipher. The AES key will be the SHA-256 hash of the data stored in
-// /etc/machine-id. This is implemented using the Go packages [crypto/aes] and [crypto/cipher].
+// by using [billgraziano]'s [Go wrapper]. On Linux, this function is equivalent to
+// "portableEncrypt()".
 //
 // [Data Protection API (DPAPI)]: https://wikipedia.org/wiki/Data_Protection_API
 // [billgraziano]: https://github.com/billgraziano
 // [Go wrapper]: https://pkg.go.dev/github.com/billgraziano/dpapi
-// [AES-GCM]: https://wikipedia.org/wiki/Galois/Counter_Mode
-func encrypt(data []byte) *[]byte {
-	key := linuxGetKey()
-
-	cipherBlock, err := aes.NewCipher(key)
-	checkError(err)
-
-	gcm, err := cipher.NewGCM(cipherBlock)
-	checkError(err)
-
-	nonce := make([]byte, gcm.NonceSize())
-	_, err = rand.Read(nonce)
-	checkError(err)
-
-	encrypted := gcm.Seal(nonce, nonce, data, nil)
-
-	zero(&key)
-	zero(&data)
-	zero(&nonce)
-
-	return &encrypted
+func nativeEncrypt(data []byte) (*[]byte, error) {
+	return portableEncrypt(data)
 }
 
-// Decrypts some data and returns a pointer pointing to the decrypted byte slice.
-// Also zeros the given data.
+// Decrypts some data written by the native backend and returns a pointer pointing to the
+// decrypted byte slice. Also zeros the given data.
 // Note: On Windows, this function will benefit from Windows's [Data Protection API (DPAPI)]
-// by using [billgraziano]'s [Go wrapper]. On Linux, this function will utilize the
-// symmetric [AES-GCM] cipher. The AES key will be the SHA-256 hash of the data stored in
-// /etc/machine-id. This is implemented using the Go packages [crypto/aes] and [crypto/cipher].
+// by using [billgraziano]'s [Go wrapper]. On Linux, vault files are always decrypted by
+// "portableDecrypt()", hence this function only decrypts legacy (headerless) data written by
+// older versions of PwdMan, which consists of a nonce and the encrypted data (the key is the
+// SHA-256 hash of /etc/machine-id, see "linuxGetLegacyKey()").
 //
 // [Data Protection API (DPAPI)]: https://wikipedia.org/wiki/Data_Protection_API
 // [billgraziano]: https://github.com/billgraziano
 // [Go wrapper]: https://pkg.go.dev/github.com/billgraziano/dpapi
-// [AES-GCM]: https://wikipedia.org/wiki/Galois/Counter_Mode
-func decrypt(data []byte) *[]byte {
-	key := linuxGetKey()
+func nativeDecrypt(data []byte) (*[]byte, error) {
+	defer zero(&data)
+
+	if isFramedVault(data) {
+		h, _, _, err := parseVault(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if h.Cipher == CIPHER_DPAPI {
+			return nil, fmt.Errorf("%w (it is protected by the Windows DPAPI)", ErrUnsupportedPlatform)
+		}
+
+		return nil, fmt.Errorf("%w (cipher %d)", ErrUnsupportedCipher, h.Cipher)
+	}
 
-	cipherBlock, err := aes.NewCipher(key)
-	checkError(err)
+	key, err := linuxGetLegacyKey()
+	if err != nil {
+		return nil, err
+	}
 
-	gcm, err := cipher.NewGCM(cipherBlock)
-	checkError(err)
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
 
 	nonceSize := gcm.NonceSize()
 
 	if len(data) < nonceSize {
-		panic(errors.New("Encrypted data size is smaller than the required nonceSize"))
+		return nil, fmt.Errorf("%w (the encrypted data is smaller than the nonce)", ErrCorruptVault)
 	}
 
-	nonce, encrypted := data[:nonceSize], data[nonceSize:]
-	decrypted, err := gcm.Open(nil, nonce, encrypted, nil)
-	checkError(err)
-
-	zero(&key)
-	zero(&data)
-	zero(&nonce)
-	zero(&encrypted)
+	decrypted, err := gcm.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w (the vault has been protected by another machine ID, see -machine-id, or it has been tampered with)", ErrWrongKey)
+	}
 
-	return &decrypted
+	return &decrypted, nil
 }
@@ -1,16 +1,82 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
 	"golang.design/x/clipboard"
 )
 
 func main() {
+	backendFlag := flag.String("backend", "", fmt.Sprintf(
+		"The encryption backend used when saving the vault: %q (DPAPI on Windows) or %q (master password, opens on both Windows and Linux). Defaults to the backend of the existing vault.",
+		BACKEND_NATIVE, BACKEND_PORTABLE,
+	))
+	vaultFlag := flag.String("vault", "", "The path of the vault. Defaults to $PWDMAN_VAULT or $XDG_DATA_HOME/pwdman/accounts.")
+	rekeyFlag := flag.Bool("rekey", false, "Change the master password of the vault (or migrate a legacy vault to a master password) and exit.")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [command]\n\nFlags:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprint(flag.CommandLine.Output(), "\n"+COMMANDS_USAGE)
+	}
+	flag.Parse()
+
+	var err error
+
+	if vaultPath, err = resolveVaultPath(*vaultFlag); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not determine the path of the vault:", err)
+		os.Exit(1)
+	}
+
+	explicitVault := len(*vaultFlag) > 0 || len(os.Getenv("PWDMAN_VAULT")) > 0
+
+	// Older versions of PwdMan stored the vault within the working directory
+	if !explicitVault {
+		if legacyPath, err := migrateLegacyVault(vaultPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not copy the vault of an older version of PwdMan: %v\nOpen it using -vault %s or move it manually.\n", err, LEGACY_VAULT_PATH)
+			os.Exit(1)
+		} else if len(legacyPath) > 0 {
+			fmt.Fprintf(os.Stderr, "The vault %s has been copied to %s, which is used from now on. "+
+				"Delete the old file once you have made sure that the copy opens.\n", legacyPath, vaultPath)
+		}
+	}
+
+	if err = loadSettings(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not read the settings:", err)
+		os.Exit(1)
+	}
+
+	switch *backendFlag {
+
+	case BACKEND_NATIVE, BACKEND_PORTABLE, "":
+		preferredBackend = *backendFlag
+		selectVault(vaultPath)
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown backend %q\n", *backendFlag)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *rekeyFlag {
+		runRekey()
+		return
+	}
+
+	// Subcommands (e.g. "list") are run without starting the TUI
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(flag.Args()))
+	}
+
 	// We need the clipboard in order to be able to copy the password
 	// GIMME ALL YOUR CLIPBOARDS
 	// ALL YOUR THINGS AND PASSWORDS TOO!
 	// Lyrics taken from 'Gimme All Your Clipboard' by 'ZZ TOP'
-	err := clipboard.Init()
-	checkError(err)
+	if clipboardErr = clipboard.Init(); clipboardErr != nil {
+		fmt.Fprintln(os.Stderr, "The clipboard is unavailable, passwords can't be copied:", clipboardErr)
+	}
 
-	setupHeadless()
+	// The vault picker is skipped if the vault has been chosen explicitly
+	setupHeadless(explicitVault)
 }
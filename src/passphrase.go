@@ -0,0 +1,116 @@
+package main
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+)
+
+// The modes of the password generator (see "pwPolicy.Mode").
+const (
+	// Random characters of the classes of the policy (see "generatePwInternal()")
+	PW_MODE_CHARACTERS = "characters"
+	// Random words of the EFF wordlist (see "generatePassphrase()")
+	PW_MODE_PASSPHRASE = "passphrase"
+)
+
+// The capitalization of the words of a passphrase (see "passphrasePolicy.Capitalize").
+const (
+	CAPITALIZE_NONE   = "none"
+	CAPITALIZE_FIRST  = "first"
+	CAPITALIZE_ALL    = "all"
+	CAPITALIZE_RANDOM = "random"
+)
+
+var capitalizations = []string{CAPITALIZE_NONE, CAPITALIZE_FIRST, CAPITALIZE_ALL, CAPITALIZE_RANDOM}
+
+// The large wordlist of the EFF (https://www.eff.org/dice). Every line consists of five dice
+// rolls and the word they select, separated by a tab.
+//
+//go:embed eff_large_wordlist.txt
+var effWordlistFile string
+
+// Returns the words of the EFF wordlist. The wordlist is parsed once when it is used first.
+var effWordlist = sync.OnceValue(func() []string {
+	words := []string{}
+
+	for line := range strings.Lines(effWordlistFile) {
+		if _, word, ok := strings.Cut(strings.TrimSpace(line), "\t"); ok {
+			words = append(words, word)
+		}
+	}
+
+	return words
+})
+
+// The settings of the passphrase mode of the password generator (see "PW_MODE_PASSPHRASE").
+type passphrasePolicy struct {
+	// The number of words
+	Words int `json:"words"`
+	// The string put between the words, may be empty
+	Separator string `json:"separator"`
+	// How the words are capitalized (see "CAPITALIZE_NONE" etc.)
+	Capitalize string `json:"capitalize"`
+	// The number of random digits and symbols (see "pwPolicy.Symbols") appended to random words
+	Digits  int `json:"digits"`
+	Symbols int `json:"symbols"`
+}
+
+// Returns the settings used for passphrases if the policy doesn't define them. Six words
+// of the EFF wordlist amount to about 77 bits of entropy.
+func defaultPassphrasePolicy() passphrasePolicy {
+	return passphrasePolicy{
+		Words:      6,
+		Separator:  "-",
+		Capitalize: CAPITALIZE_NONE,
+	}
+}
+
+// Generates a passphrase according to the given policy (see "passphrasePolicy") and returns the
+// pointer pointing to its byte slice. The words are chosen from the EFF wordlist, afterwards the
+// digits and symbols are appended to randomly chosen words. The policy has to be valid
+// (see "pwPolicy.validate()"). The utilized RNG is the cryptographically secure RNG implemented
+// in the [crypto/rand] package.
+//
+// Don't use this function directly, use the "generatePw()" function instead.
+func generatePassphrase(policy pwPolicy) *[]byte {
+	pp := policy.Passphrase
+	wordlist := effWordlist()
+	words := make([]string, pp.Words)
+
+	for i := range words {
+		word := wordlist[randomInt(len(wordlist))]
+
+		switch pp.Capitalize {
+
+		case CAPITALIZE_FIRST:
+			word = strings.ToUpper(word[:1]) + word[1:]
+
+		case CAPITALIZE_ALL:
+			word = strings.ToUpper(word)
+
+		case CAPITALIZE_RANDOM:
+			if randomInt(2) == 1 {
+				word = strings.ToUpper(word[:1]) + word[1:]
+			}
+		}
+
+		words[i] = word
+	}
+
+	digits, symbols := policy.chars(DIGIT_CHARS), policy.chars(policy.Symbols)
+
+	for range pp.Digits {
+		i := randomInt(len(words))
+		words[i] += string(digits[randomInt(len(digits))])
+	}
+
+	for range pp.Symbols {
+		i := randomInt(len(words))
+		words[i] += string(symbols[randomInt(len(symbols))])
+	}
+
+	buf := []byte(strings.Join(words, pp.Separator))
+
+	return &buf
+}
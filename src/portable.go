@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Parameters of the Argon2id key derivation function used to derive the AES key
+// from the master password. Refer to [RFC 9106] for more information.
+//
+// [RFC 9106]: https://www.rfc-editor.org/rfc/rfc9106.html
+const (
+	ARGON2_TIME    uint32 = 3
+	ARGON2_MEMORY  uint32 = 64 * 1024
+	ARGON2_THREADS uint8  = 4
+	KEY_SIZE       uint32 = 32
+	SALT_SIZE      int    = 16
+	GCM_NONCE_SIZE int    = 12
+)
+
+// Upper bounds of the Argon2id parameters accepted from a vault header (see "checkKDFParams()"),
+// so that a damaged or hostile vault can't crash PwdMan or exhaust the memory of the machine
+// before the authentication tag of the vault has been checked. The lower bounds are the
+// parameters written by PwdMan, so that a tampered header can't downgrade the cost either.
+const (
+	ARGON2_MAX_TIME    uint32 = 16
+	ARGON2_MAX_MEMORY  uint32 = 1024 * 1024
+	ARGON2_MAX_THREADS uint8  = 64
+)
+
+// Returns an error wrapping "ErrCorruptVault" if the given Argon2id parameters are out of range.
+func checkKDFParams(time uint32, memory uint32, threads uint8) error {
+	if time < ARGON2_TIME || time > ARGON2_MAX_TIME || threads == 0 || threads > ARGON2_MAX_THREADS ||
+		memory < max(ARGON2_MEMORY, 8*uint32(threads)) || memory > ARGON2_MAX_MEMORY {
+		return fmt.Errorf("%w (the key derivation parameters are out of range)", ErrCorruptVault)
+	}
+
+	return nil
+}
+
+// Derives the AES key from the master password and the given salt using Argon2id
+// with the given parameters. Returns "ErrNoMasterPw" if no master password has been supplied
+// and "ErrCorruptVault" if the parameters are out of range (see "checkKDFParams()").
+func deriveKey(salt []byte, time uint32, memory uint32, threads uint8) ([]byte, error) {
+	if len(masterPw) == 0 {
+		return nil, ErrNoMasterPw
+	}
+
+	if err := checkKDFParams(time, memory, threads); err != nil {
+		return nil, err
+	}
+
+	return argon2.IDKey(masterPw, salt, time, memory, threads, KEY_SIZE), nil
+}
+
+// Returns an AES-GCM instance using the given key. Also zeros the given key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	defer zero(&key)
+
+	cipherBlock, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(cipherBlock)
+}
+
+// Derives the key from the master password and the given header parameters (see "deriveKey()")
+// and returns an AES-GCM instance using that key.
+func newPortableGCM(salt []byte, time uint32, memory uint32, threads uint8) (cipher.AEAD, error) {
+	key, err := deriveKey(salt, time, memory, threads)
+	if err != nil {
+		return nil, err
+	}
+
+	return newGCM(key)
+}
+
+// Encrypts some data using the portable backend and returns a pointer pointing to the
+// encrypted byte slice which is prefixed with a vault header (see "VAULT_VERSION").
+// Also zeros the given data.
+// The portable backend utilizes the symmetric [AES-GCM] cipher. The AES key will be derived
+// from the master password and a random salt using [Argon2id]. The salt and the KDF parameters
+// are stored in the header which is authenticated as additional data. As the key does not depend
+// on the machine or the operating system, the resulting file can be opened on both Windows and Linux.
+// This is implemented using the Go packages [crypto/aes], [crypto/cipher] and [argon2].
+//
+// [AES-GCM]: https://wikipedia.org/wiki/Galois/Counter_Mode
+// [Argon2id]: https://wikipedia.org/wiki/Argon2
+// [argon2]: https://pkg.go.dev/golang.org/x/crypto/argon2
+func portableEncrypt(data []byte) (*[]byte, error) {
+	defer zero(&data)
+
+	h := vaultHeader{
+		Version:    VAULT_VERSION,
+		KDF:        KDF_ARGON2ID,
+		KDFTime:    ARGON2_TIME,
+		KDFMemory:  ARGON2_MEMORY,
+		KDFThreads: ARGON2_THREADS,
+		Salt:       make([]byte, SALT_SIZE),
+		Cipher:     CIPHER_AES_256_GCM,
+	}
+
+	if _, err := rand.Read(h.Salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newPortableGCM(h.Salt, h.KDFTime, h.KDFMemory, h.KDFThreads)
+	if err != nil {
+		return nil, err
+	}
+
+	h.Nonce = make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(h.Nonce); err != nil {
+		return nil, err
+	}
+
+	header := h.marshal()
+	encrypted := gcm.Seal(header, h.Nonce, data, header)
+
+	return &encrypted, nil
+}
+
+// Decrypts the ciphertext of a vault file written by the portable backend and returns a
+// pointer pointing to the decrypted byte slice. The raw header bytes are required as they
+// are authenticated as additional data. Refer to "portableEncrypt()" for more information.
+// As AES-GCM can't tell a wrong key from modified data, "ErrWrongKey" is returned in both cases.
+func portableDecrypt(h *vaultHeader, header []byte, encrypted []byte) (*[]byte, error) {
+	if h.KDF != KDF_ARGON2ID {
+		return nil, fmt.Errorf("%w (the portable backend requires Argon2id)", ErrUnsupportedCipher)
+	}
+
+	gcm, err := newPortableGCM(h.Salt, h.KDFTime, h.KDFMemory, h.KDFThreads)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(h.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("%w (the nonce stored in the header has an invalid size)", ErrCorruptVault)
+	}
+
+	decrypted, err := gcm.Open(nil, h.Nonce, encrypted, header)
+	if err != nil {
+		return nil, fmt.Errorf("%w (wrong master password or the vault has been tampered with)", ErrWrongKey)
+	}
+
+	return &decrypted, nil
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// The sources of the breach check (see "breachCheckSettings").
+const (
+	// Queries the pwned passwords API of Have I Been Pwned (see "hibpChecker")
+	BREACH_SOURCE_ONLINE = "online"
+	// Searches a downloaded copy of the Pwned Passwords database (see "offlineChecker")
+	BREACH_SOURCE_OFFLINE = "offline"
+	// Disables the breach check (see "noopChecker")
+	BREACH_SOURCE_NONE = "none"
+)
+
+// Identifies PwdMan to the pwned passwords API, as requested by Have I Been Pwned.
+const BREACH_USER_AGENT = "PwdMan (+https://github.com/m1ck6x/PwdMan)"
+
+// The delay before the first retry of a failed request to the pwned passwords API.
+// The delay is doubled for every further retry.
+const BREACH_RETRY_BACKOFF = 500 * time.Millisecond
+
+// The length of the hashes of the Pwned Passwords database in hex characters, which
+// determines the hash function used to search it.
+const (
+	SHA1_HEX_LENGTH = 40
+	NTLM_HEX_LENGTH = 32
+)
+
+// Once the searched range of the offline database is smaller than this number of bytes,
+// it is scanned line by line instead of being bisected any further.
+const PWNED_SCAN_THRESHOLD = 4096
+
+// Returned when passwords are audited although the breach check has been disabled.
+var ErrBreachCheckDisabled = errors.New("The breach check has been disabled in the settings")
+
+// Checks whether passwords have appeared in a data breach. The checker used by PwdMan is
+// chosen by the settings (see "breachChecker").
+type BreachChecker interface {
+	// Returns how many times the given password has appeared in a data breach.
+	PwnedCount(pw []byte) (uint32, error)
+}
+
+// The breach checker configured in the user settings, see "newBreachChecker()".
+var breachChecker = newBreachChecker(userSettings.BreachCheck)
+
+// Returns the breach checker for the given (valid) settings.
+func newBreachChecker(s breachCheckSettings) BreachChecker {
+	switch s.Source {
+
+	case BREACH_SOURCE_OFFLINE:
+		return offlineChecker{path: s.Database}
+
+	case BREACH_SOURCE_NONE:
+		return noopChecker{}
+	}
+
+	return hibpChecker{
+		baseURL: strings.TrimSuffix(s.BaseURL, "/"),
+		client:  &http.Client{Timeout: time.Duration(s.TimeoutSeconds) * time.Second},
+		retries: s.Retries,
+		backoff: BREACH_RETRY_BACKOFF,
+		padding: s.Padding,
+	}
+}
+
+// Returns an error if the breach check settings are invalid.
+func (s breachCheckSettings) validate() error {
+	switch s.Source {
+
+	case BREACH_SOURCE_ONLINE:
+		if u, err := url.Parse(s.BaseURL); err != nil || u.Scheme != "https" && u.Scheme != "http" || len(u.Host) == 0 {
+			return fmt.Errorf("Invalid base URL %q", s.BaseURL)
+		}
+
+		if s.TimeoutSeconds < 0 || s.Retries < 0 {
+			return errors.New("The timeout and the number of retries must not be negative")
+		}
+
+		return nil
+
+	case BREACH_SOURCE_OFFLINE:
+		if len(s.Database) == 0 {
+			return errors.New("The offline source requires the path of the Pwned Passwords database")
+		}
+
+		return nil
+
+	case BREACH_SOURCE_NONE:
+		return nil
+	}
+
+	return fmt.Errorf("Unknown source %q (expected %q, %q or %q)", s.Source, BREACH_SOURCE_ONLINE, BREACH_SOURCE_OFFLINE, BREACH_SOURCE_NONE)
+}
+
+// Returns a human-readable description of the source of the breach check, e.g. "Have I Been Pwned".
+func (s breachCheckSettings) describe() string {
+	switch s.Source {
+
+	case BREACH_SOURCE_OFFLINE:
+		return "the Pwned Passwords database " + s.Database
+
+	case BREACH_SOURCE_NONE:
+		return "nothing (the breach check has been disabled)"
+	}
+
+	return "Have I Been Pwned"
+}
+
+// Checks passwords against [HIBPs' password search by range]. Only the first 5 characters of
+// the SHA-1 hash of a password are sent (k-anonymity), the remaining characters are compared
+// locally. If padding is enabled, the response is padded with random hashes, so that its size
+// doesn't reveal the prefix either.
+//
+// [HIBPs' password search by range]: https://haveibeenpwned.com/API/v3#SearchingPwnedPasswordsByRange
+type hibpChecker struct {
+	// The URL of the API without the trailing "/range/{prefix}"
+	baseURL string
+	client  *http.Client
+	// The number of times a failed request is retried (see "BREACH_RETRY_BACKOFF")
+	retries int
+	backoff time.Duration
+	padding bool
+}
+
+func (c hibpChecker) PwnedCount(pw []byte) (uint32, error) {
+	hash := sha1Hash(pw)
+	prefix, suffix := hash[:5], hash[5:]
+
+	data, err := c.fetchRange(prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	// Padding entries have a count of 0, so they are treated like unknown hashes
+	for entry := range strings.SplitSeq(data, "\r\n") {
+		entrySuffix, count, found := strings.Cut(entry, ":")
+
+		if found && entrySuffix == suffix {
+			n, err := strconv.ParseUint(strings.TrimSpace(count), 10, 32)
+			return uint32(n), err
+		}
+	}
+
+	return 0, nil
+}
+
+// Returns the suffixes of all hashes starting with the given prefix. Requests which failed
+// because of a network error, rate limiting or a server error are retried with exponential backoff.
+func (c hibpChecker) fetchRange(prefix string) (string, error) {
+	var err error
+
+	for attempt := range c.retries + 1 {
+		if attempt > 0 {
+			time.Sleep(c.backoff << (attempt - 1))
+		}
+
+		var data string
+		var retry bool
+
+		if data, retry, err = c.requestRange(prefix); err == nil || !retry {
+			return data, err
+		}
+	}
+
+	return "", err
+}
+
+// Requests the suffixes of all hashes starting with the given prefix once. The second
+// return value indicates whether the request may succeed if it is retried.
+func (c hibpChecker) requestRange(prefix string) (string, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return "", false, err
+	}
+
+	req.Header.Set("User-Agent", BREACH_USER_AGENT)
+
+	if c.padding {
+		req.Header.Set("Add-Padding", "true")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", true, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		return "", retry, fmt.Errorf("Have I Been Pwned responded with %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+
+	return string(data), true, err
+}
+
+// Checks passwords against a downloaded copy of the Pwned Passwords database, see "offlinePwnedCount()".
+type offlineChecker struct {
+	path string
+}
+
+func (c offlineChecker) PwnedCount(pw []byte) (uint32, error) {
+	return offlinePwnedCount(c.path, pw)
+}
+
+// Treats every password as unknown. Used if the breach check has been disabled.
+type noopChecker struct{}
+
+func (noopChecker) PwnedCount([]byte) (uint32, error) {
+	return 0, nil
+}
+
+// Returns the SHA-1 hash of the given password as uppercase hex, as used by Have I Been Pwned.
+func sha1Hash(pw []byte) string {
+	// deepcode ignore InsecureHash: The pwned passwords API only supports SHA-1 or NTLM hashes. The hash is only used for looking up the password, relativizing this issue.
+	sum := sha1.Sum(pw)
+
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// Returns the NTLM hash (MD4 of the UTF-16LE encoding) of the given password as uppercase hex,
+// as used by the NTLM variant of the Pwned Passwords database.
+func ntlmHash(pw []byte) string {
+	encoded := []byte{}
+
+	for _, c := range utf16.Encode([]rune(string(pw))) {
+		encoded = append(encoded, byte(c), byte(c>>8))
+	}
+
+	defer zero(&encoded)
+
+	// deepcode ignore InsecureHash: See "sha1Hash()".
+	h := md4.New()
+	h.Write(encoded)
+
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+}
+
+// Returns how many times the given password has appeared in a data breach according to the
+// downloaded Pwned Passwords database at the given path. The database has to be ordered by hash,
+// one "HASH:COUNT" line per password, as produced by the [PwnedPasswordsDownloader]. Both the
+// SHA-1 and the NTLM variant are supported, the variant is detected by the length of the hashes.
+// The database is bisected on disk, so that only a few kilobytes of it are read per password.
+//
+// [PwnedPasswordsDownloader]: https://github.com/HaveIBeenPwned/PwnedPasswordsDownloader
+func offlinePwnedCount(path string, pw []byte) (uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	size := info.Size()
+
+	first, _, err := lineAfter(f, size, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	var hash string
+
+	switch firstHash, _, _ := strings.Cut(first, ":"); len(firstHash) {
+
+	case SHA1_HEX_LENGTH:
+		hash = sha1Hash(pw)
+
+	case NTLM_HEX_LENGTH:
+		hash = ntlmHash(pw)
+
+	default:
+		return 0, fmt.Errorf("%s is not a Pwned Passwords database (expected SHA-1 or NTLM hashes)", path)
+	}
+
+	return searchDatabase(f, size, hash)
+}
+
+// Returns the count of the line of the given hash within the database, or 0 if the database
+// doesn't contain the hash. The database is bisected until the remaining range is small
+// enough to be scanned (see "PWNED_SCAN_THRESHOLD").
+func searchDatabase(f *os.File, size int64, hash string) (uint32, error) {
+	// All lines starting before lo are smaller than the hash and all lines starting at hi or
+	// later are greater than or equal to it. lo is always the start of a line.
+	lo, hi := int64(0), size
+
+	for hi-lo > PWNED_SCAN_THRESHOLD {
+		mid := lo + (hi-lo)/2
+
+		line, start, err := lineAfter(f, size, mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if start < size && compareHash(line, hash) < 0 {
+			lo = start
+		} else {
+			hi = mid
+		}
+	}
+
+	scanner := bufio.NewScanner(io.NewSectionReader(f, lo, size-lo))
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch compareHash(line, hash) {
+
+		case 0:
+			_, count, _ := strings.Cut(line, ":")
+			n, err := strconv.ParseUint(strings.TrimSpace(count), 10, 32)
+
+			return uint32(n), err
+
+		case 1:
+			return 0, nil
+		}
+	}
+
+	return 0, scanner.Err()
+}
+
+// Returns the first line of the database starting at or after the given offset together with
+// its offset. If there is no such line, the size of the database is returned as offset.
+func lineAfter(f *os.File, size int64, offset int64) (string, int64, error) {
+	start := max(offset-1, 0)
+	r := bufio.NewReader(io.NewSectionReader(f, start, size-start))
+
+	// Skips the rest of the line the offset points into, unless the offset is the start of a line
+	if offset > 0 {
+		skipped, err := r.ReadString('\n')
+		start += int64(len(skipped))
+
+		if err == io.EOF {
+			return "", size, nil
+		} else if err != nil {
+			return "", 0, err
+		}
+	}
+
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", 0, err
+	}
+
+	return strings.TrimSpace(line), start, nil
+}
+
+// Compares the hash of the given "HASH:COUNT" line of the database with the given hash,
+// similar to "strings.Compare()".
+func compareHash(line string, hash string) int {
+	lineHash, _, _ := strings.Cut(line, ":")
+
+	return strings.Compare(strings.ToUpper(strings.TrimSpace(lineHash)), hash)
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// The hashes of "password", see "sha1Hash()" and "ntlmHash()".
+const (
+	PASSWORD_SHA1 = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+	PASSWORD_NTLM = "8846F7EAEE8FB117AD06BDD830B7586C"
+)
+
+// Returns a checker for the given test server which retries quickly.
+func testChecker(server *httptest.Server, retries int) hibpChecker {
+	return hibpChecker{
+		baseURL: server.URL,
+		client:  server.Client(),
+		retries: retries,
+		backoff: time.Millisecond,
+		padding: true,
+	}
+}
+
+func TestHashes(t *testing.T) {
+	if got := sha1Hash([]byte("password")); got != PASSWORD_SHA1 {
+		t.Fatalf("sha1Hash() = %s, want %s", got, PASSWORD_SHA1)
+	}
+
+	if got := ntlmHash([]byte("password")); got != PASSWORD_NTLM {
+		t.Fatalf("ntlmHash() = %s, want %s", got, PASSWORD_NTLM)
+	}
+}
+
+func TestHIBPCheckerRequest(t *testing.T) {
+	for _, padding := range []bool{true, false} {
+		t.Run(fmt.Sprintf("padding %v", padding), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/range/"+PASSWORD_SHA1[:5] {
+					t.Errorf("Requested %s", r.URL.Path)
+				}
+
+				if got := r.Header.Get("User-Agent"); got != BREACH_USER_AGENT {
+					t.Errorf("User-Agent = %q, want %q", got, BREACH_USER_AGENT)
+				}
+
+				if got, want := r.Header.Get("Add-Padding"), map[bool]string{true: "true"}[padding]; got != want {
+					t.Errorf("Add-Padding = %q, want %q", got, want)
+				}
+
+				fmt.Fprintf(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n%s:10434004\r\n", PASSWORD_SHA1[5:])
+			}))
+			defer server.Close()
+
+			checker := testChecker(server, 0)
+			checker.padding = padding
+
+			if count, err := checker.PwnedCount([]byte("password")); err != nil || count != 10434004 {
+				t.Fatalf("PwnedCount() = %d, %v, want 10434004", count, err)
+			}
+		})
+	}
+}
+
+func TestHIBPCheckerPadding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Padding entries have a count of 0 and may even match the suffix of the password
+		fmt.Fprintf(w, "0018A45C4D1DEF81644B54AB7F969B88D65:0\r\n%s:0\r\nFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:0", PASSWORD_SHA1[5:])
+	}))
+	defer server.Close()
+
+	if count, err := testChecker(server, 0).PwnedCount([]byte("password")); err != nil || count != 0 {
+		t.Fatalf("PwnedCount() = %d, %v, want 0", count, err)
+	}
+
+	if count, err := testChecker(server, 0).PwnedCount([]byte("unknown")); err != nil || count != 0 {
+		t.Fatalf("PwnedCount() = %d, %v, want 0", count, err)
+	}
+}
+
+func TestHIBPCheckerRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		// The number of expected requests
+		requests int
+		wantErr  bool
+	}{
+		{"success", []int{http.StatusOK}, 2, 1, false},
+		{"rate limited", []int{http.StatusTooManyRequests, http.StatusOK}, 2, 2, false},
+		{"server errors", []int{http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusOK}, 2, 3, false},
+		{"retries exhausted", []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK}, 1, 2, true},
+		{"no retries", []int{http.StatusTooManyRequests, http.StatusOK}, 0, 1, true},
+		{"not found", []int{http.StatusNotFound, http.StatusOK}, 2, 1, true},
+		{"bad request", []int{http.StatusBadRequest, http.StatusOK}, 2, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[min(int(requests.Add(1)), len(tt.statuses))-1]
+				w.WriteHeader(status)
+
+				if status == http.StatusOK {
+					fmt.Fprintf(w, "%s:3", PASSWORD_SHA1[5:])
+				}
+			}))
+			defer server.Close()
+
+			count, err := testChecker(server, tt.retries).PwnedCount([]byte("password"))
+
+			if (err != nil) != tt.wantErr || (err == nil && count != 3) {
+				t.Fatalf("PwnedCount() = %d, %v, want error %v", count, err, tt.wantErr)
+			}
+
+			if got := int(requests.Load()); got != tt.requests {
+				t.Fatalf("%d requests, want %d", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestHIBPCheckerBackoff(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	checker := testChecker(server, 2)
+	checker.backoff = 20 * time.Millisecond
+
+	if _, err := checker.PwnedCount([]byte("password")); err == nil {
+		t.Fatal("PwnedCount() succeeded although every request has been rate limited")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(times) != 3 {
+		t.Fatalf("%d requests, want 3", len(times))
+	}
+
+	// The delay is doubled for every retry
+	for i, want := range []time.Duration{checker.backoff, 2 * checker.backoff} {
+		if got := times[i+1].Sub(times[i]); got < want {
+			t.Fatalf("Retry %d after %v, want at least %v", i+1, got, want)
+		}
+	}
+}
+
+func TestHIBPCheckerTimeout(t *testing.T) {
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	checker := testChecker(server, 0)
+	checker.client = &http.Client{Timeout: 50 * time.Millisecond}
+
+	start := time.Now()
+
+	if _, err := checker.PwnedCount([]byte("password")); err == nil {
+		t.Fatal("PwnedCount() succeeded although the server never responded")
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("PwnedCount() returned after %v", elapsed)
+	}
+}
+
+func TestNewBreachChecker(t *testing.T) {
+	checker := newBreachChecker(breachCheckSettings{
+		Source:         BREACH_SOURCE_ONLINE,
+		BaseURL:        "https://api.pwnedpasswords.com/",
+		TimeoutSeconds: 7,
+		Retries:        3,
+		Padding:        true,
+	})
+
+	hibp, ok := checker.(hibpChecker)
+	if !ok || hibp.baseURL != "https://api.pwnedpasswords.com" || hibp.client.Timeout != 7*time.Second || hibp.retries != 3 || !hibp.padding {
+		t.Fatalf("newBreachChecker() = %+v", checker)
+	}
+
+	if _, ok := newBreachChecker(breachCheckSettings{Source: BREACH_SOURCE_OFFLINE, Database: "db"}).(offlineChecker); !ok {
+		t.Fatal("newBreachChecker() didn't return an offline checker")
+	}
+
+	if _, ok := newBreachChecker(breachCheckSettings{Source: BREACH_SOURCE_NONE}).(noopChecker); !ok {
+		t.Fatal("newBreachChecker() didn't return a no-op checker")
+	}
+}
+
+// Writes a Pwned Passwords database containing the given hashes to a temporary file and returns
+// its path. The count of every hash is its index + 1.
+func writeDatabase(t *testing.T, hashes []string, newline string) string {
+	hashes = slices.Sorted(slices.Values(hashes))
+
+	var b strings.Builder
+
+	for i, hash := range hashes {
+		fmt.Fprintf(&b, "%s:%d%s", hash, i+1, newline)
+	}
+
+	path := filepath.Join(t.TempDir(), "pwned-passwords.txt")
+
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestOfflinePwnedCount(t *testing.T) {
+	pws := []string{}
+
+	for i := range 5000 {
+		pws = append(pws, fmt.Sprintf("pw%d", i))
+	}
+
+	tests := []struct {
+		name    string
+		hash    func(pw []byte) string
+		pws     []string
+		newline string
+	}{
+		// Large enough to be bisected
+		{"sha1", sha1Hash, pws, "\n"},
+		{"ntlm", ntlmHash, pws, "\r\n"},
+		// Smaller than "PWNED_SCAN_THRESHOLD", hence scanned right away
+		{"small sha1", sha1Hash, pws[:20], "\r\n"},
+		{"small ntlm", ntlmHash, pws[:20], "\n"},
+		{"single line", sha1Hash, pws[:1], ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hashes := []string{}
+
+			for _, pw := range tt.pws {
+				hashes = append(hashes, tt.hash([]byte(pw)))
+			}
+
+			path := writeDatabase(t, hashes, tt.newline)
+			sorted := slices.Sorted(slices.Values(hashes))
+
+			if info, _ := os.Stat(path); (info.Size() > PWNED_SCAN_THRESHOLD) != (len(tt.pws) > 20) {
+				t.Fatalf("Unexpected size of the database: %d bytes", info.Size())
+			}
+
+			for _, pw := range tt.pws {
+				want := uint32(slices.Index(sorted, tt.hash([]byte(pw))) + 1)
+
+				if count, err := offlinePwnedCount(path, []byte(pw)); err != nil || count != want {
+					t.Fatalf("offlinePwnedCount(%q) = %d, %v, want %d", pw, count, err, want)
+				}
+			}
+
+			for _, pw := range []string{"password", "pw-unknown", ""} {
+				if count, err := offlinePwnedCount(path, []byte(pw)); err != nil || count != 0 {
+					t.Fatalf("offlinePwnedCount(%q) = %d, %v, want 0", pw, count, err)
+				}
+			}
+		})
+	}
+}
+
+func TestOfflinePwnedCountBoundaries(t *testing.T) {
+	// The first and the last line of the database as well as hashes before and after all lines
+	path := writeDatabase(t, []string{
+		strings.Repeat("1", SHA1_HEX_LENGTH),
+		PASSWORD_SHA1,
+		strings.Repeat("E", SHA1_HEX_LENGTH),
+	}, "\n")
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	info, _ := f.Stat()
+
+	tests := []struct {
+		hash string
+		want uint32
+	}{
+		{strings.Repeat("0", SHA1_HEX_LENGTH), 0},
+		{strings.Repeat("1", SHA1_HEX_LENGTH), 1},
+		{PASSWORD_SHA1, 2},
+		{strings.Repeat("E", SHA1_HEX_LENGTH), 3},
+		{strings.Repeat("F", SHA1_HEX_LENGTH), 0},
+	}
+
+	for _, tt := range tests {
+		if count, err := searchDatabase(f, info.Size(), tt.hash); err != nil || count != tt.want {
+			t.Fatalf("searchDatabase(%s) = %d, %v, want %d", tt.hash, count, err, tt.want)
+		}
+	}
+}
+
+func TestOfflinePwnedCountInvalidDatabase(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"empty":    "",
+		"text":     "hello world\n",
+		"md5":      strings.Repeat("A", 31) + ":1\n",
+		"too long": strings.Repeat("A", SHA1_HEX_LENGTH+1) + ":1\n",
+	} {
+		path := filepath.Join(dir, name)
+
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := offlinePwnedCount(path, []byte("password")); err == nil {
+			t.Fatalf("offlinePwnedCount() of a %s database succeeded", name)
+		}
+	}
+
+	if _, err := offlinePwnedCount(filepath.Join(dir, "missing"), []byte("password")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("offlinePwnedCount() of a missing database: error = %v, want %v", err, os.ErrNotExist)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// The kinds of password reuse found by "findReuse()".
+const (
+	// Several accounts share the same password
+	REUSE_IDENTICAL = "identical"
+	// Several accounts use nearly the same password
+	REUSE_SIMILAR = "similar"
+)
+
+// The reasons why passwords are considered similar (see "similarity()").
+const (
+	// The passwords only differ by a few characters or by their case, e.g. "Hunter42" and "hunter43"
+	SIMILAR_EDIT_DISTANCE = "edit distance"
+	// The passwords share the same base and only differ by their suffixes, e.g. "Summer2023!" and "summer24"
+	SIMILAR_COMMON_BASE = "common base"
+)
+
+// Passwords are similar if they differ by at most this number of characters. Only passwords
+// which are at least "SIMILAR_MIN_LENGTH" characters long are compared this way, as short
+// passwords are similar by chance.
+const (
+	SIMILAR_MAX_DISTANCE = 2
+	SIMILAR_MIN_LENGTH   = 6
+)
+
+// The minimum length of the base shared by passwords which only differ by their suffixes.
+const SIMILAR_MIN_BASE_LENGTH = 4
+
+// A group of accounts which share the same or nearly the same password.
+type reuseGroup struct {
+	// Either "REUSE_IDENTICAL" or "REUSE_SIMILAR"
+	Kind string
+	// Why the passwords are considered similar (see "SIMILAR_EDIT_DISTANCE" etc.)
+	Reasons  []string
+	Accounts []account
+}
+
+// Finds the accounts which share the same password and the accounts whose passwords are
+// nearly identical (see "similarity()"). Groups of identical passwords are returned first,
+// larger groups before smaller ones. Accounts without a password are skipped.
+func findReuse(accounts []account) []reuseGroup {
+	// The distinct passwords in order of their first appearance
+	pws := []string{}
+	byPw := map[string][]account{}
+
+	for _, a := range accounts {
+		if len(a.Pw) == 0 {
+			continue
+		}
+
+		if _, ok := byPw[a.Pw]; !ok {
+			pws = append(pws, a.Pw)
+		}
+
+		byPw[a.Pw] = append(byPw[a.Pw], a)
+	}
+
+	identical, similar := []reuseGroup{}, []reuseGroup{}
+
+	for _, pw := range pws {
+		if len(byPw[pw]) > 1 {
+			identical = append(identical, reuseGroup{Kind: REUSE_IDENTICAL, Accounts: byPw[pw]})
+		}
+	}
+
+	// Similar passwords are clustered transitively, i.e. if a is similar to b and b to c,
+	// all three form a single group
+	parents := make([]int, len(pws))
+	reasons := make([][]string, len(pws))
+	folded := make([][]rune, len(pws))
+
+	for i, pw := range pws {
+		parents[i] = i
+		folded[i] = []rune(strings.ToLower(pw))
+	}
+
+	root := func(i int) int {
+		for parents[i] != i {
+			parents[i] = parents[parents[i]]
+			i = parents[i]
+		}
+
+		return i
+	}
+
+	for i := range pws {
+		for j := i + 1; j < len(pws); j++ {
+			reason := similarity(folded[i], folded[j])
+			if len(reason) == 0 {
+				continue
+			}
+
+			ri, rj := root(i), root(j)
+
+			if ri != rj {
+				parents[rj] = ri
+				reasons[ri] = append(reasons[ri], reasons[rj]...)
+			}
+
+			reasons[ri] = append(reasons[ri], reason)
+		}
+	}
+
+	members := map[int][]int{}
+
+	for i := range pws {
+		members[root(i)] = append(members[root(i)], i)
+	}
+
+	for i := range pws {
+		if root(i) != i || len(members[i]) < 2 {
+			continue
+		}
+
+		g := reuseGroup{Kind: REUSE_SIMILAR, Reasons: slices.Compact(slices.Sorted(slices.Values(reasons[i])))}
+
+		for _, member := range members[i] {
+			g.Accounts = append(g.Accounts, byPw[pws[member]]...)
+		}
+
+		similar = append(similar, g)
+	}
+
+	byCount := func(a, b reuseGroup) int {
+		return cmp.Compare(len(b.Accounts), len(a.Accounts))
+	}
+
+	slices.SortStableFunc(identical, byCount)
+	slices.SortStableFunc(similar, byCount)
+
+	return append(identical, similar...)
+}
+
+// Returns a human-readable description of the group, e.g. "Similar (common base)".
+func (g reuseGroup) describe() string {
+	if g.Kind == REUSE_IDENTICAL {
+		return "Identical"
+	}
+
+	return "Similar (" + strings.Join(g.Reasons, ", ") + ")"
+}
+
+// Returns why the given (distinct) passwords are considered similar, or an empty string if
+// they aren't. The passwords have to be lowercased, so that they are compared case-insensitively.
+func similarity(a []rune, b []rune) string {
+	if baseA := passwordBase(a); len(baseA) >= SIMILAR_MIN_BASE_LENGTH && slices.Equal(baseA, passwordBase(b)) {
+		return SIMILAR_COMMON_BASE
+	}
+
+	if min(len(a), len(b)) >= SIMILAR_MIN_LENGTH && editDistance(a, b, SIMILAR_MAX_DISTANCE) <= SIMILAR_MAX_DISTANCE {
+		return SIMILAR_EDIT_DISTANCE
+	}
+
+	return ""
+}
+
+// Returns the given password without its suffix of digits and symbols, e.g. "summer" for "summer2023!".
+func passwordBase(pw []rune) []rune {
+	end := len(pw)
+
+	for end > 0 && !unicode.IsLetter(pw[end-1]) {
+		end--
+	}
+
+	return pw[:end]
+}
+
+// Returns the Levenshtein distance of the given strings. As only small distances are of interest,
+// the computation stops as soon as the distance exceeds the given limit, in which case limit+1
+// is returned.
+func editDistance(a []rune, b []rune, limit int) int {
+	if len(a)-len(b) > limit || len(b)-len(a) > limit {
+		return limit + 1
+	}
+
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+
+		if rowMin > limit {
+			return limit + 1
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return min(prev[len(b)], limit+1)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// The user settings. They are stored unencrypted in JSON format in the settings file
+// (see "settingsPath()") and must therefore never contain any secrets. Missing fields keep their
+// default values (see "defaultSettings()").
+type settings struct {
+	Backups     backupSettings      `json:"backups"`
+	Sort        sortSettings        `json:"sort"`
+	History     historySettings     `json:"history"`
+	Trash       trashSettings       `json:"trash"`
+	Generator   pwPolicy            `json:"generator"`
+	Audit       auditSettings       `json:"audit"`
+	BreachCheck breachCheckSettings `json:"breachCheck"`
+}
+
+// The retention policy of the automatic vault snapshots (see "backups.go").
+type backupSettings struct {
+	// The maximum number of snapshots to keep. 0 disables the snapshots entirely.
+	Count int `json:"count"`
+	// The maximum age of a snapshot in days. 0 keeps snapshots regardless of their age.
+	MaxAgeDays int `json:"maxAgeDays"`
+}
+
+// The order of the accounts table (see "headless_sort.go"). The order is changed from within
+// the TUI and saved right away (see "saveSettings()").
+type sortSettings struct {
+	// The column the accounts are sorted by (see "SORT_SERVICE" etc.). If empty, the accounts
+	// are shown in the order they have been added.
+	Column string `json:"column"`
+	// Whether the accounts are sorted in descending order.
+	Descending bool `json:"descending"`
+}
+
+// The password history of the accounts (see "recordPwChange()").
+type historySettings struct {
+	// The maximum number of previous passwords kept per account. 0 disables the history.
+	Count int `json:"count"`
+}
+
+// The retention policy of the deleted accounts (see "trash.go").
+type trashSettings struct {
+	// The number of days after which deleted accounts are purged from the trash.
+	// 0 keeps deleted accounts until they are deleted permanently.
+	RetentionDays int `json:"retentionDays"`
+}
+
+// The breach check of the stored passwords (see "audit.go").
+type auditSettings struct {
+	// The number of days after which passwords are checked again, as new breaches are published
+	// regularly. 0 only checks passwords again once they have been changed.
+	MaxAgeDays int `json:"maxAgeDays"`
+}
+
+// The source generated and stored passwords are checked against (see "BreachChecker").
+type breachCheckSettings struct {
+	// Either "BREACH_SOURCE_ONLINE", "BREACH_SOURCE_OFFLINE" or "BREACH_SOURCE_NONE".
+	Source string `json:"source"`
+	// The URL of the pwned passwords API used by the online source, e.g. of a mirror.
+	BaseURL string `json:"baseURL"`
+	// The timeout of a single request to the API in seconds. 0 disables the timeout.
+	TimeoutSeconds int `json:"timeoutSeconds"`
+	// The number of times a failed request to the API is retried.
+	Retries int `json:"retries"`
+	// Whether the responses of the API are padded with random hashes (see "hibpChecker").
+	Padding bool `json:"padding"`
+	// The path of the downloaded Pwned Passwords database used by the offline source
+	// (see "offlinePwnedCount()").
+	Database string `json:"database"`
+}
+
+// The currently active user settings, loaded by "loadSettings()".
+var userSettings = defaultSettings()
+
+// Returns the settings used if the settings file does not exist or lacks some fields.
+func defaultSettings() settings {
+	return settings{
+		Backups: backupSettings{
+			Count:      10,
+			MaxAgeDays: 90,
+		},
+		History: historySettings{
+			Count: 10,
+		},
+		Trash: trashSettings{
+			RetentionDays: 30,
+		},
+		Generator: defaultPwPolicy(),
+		Audit: auditSettings{
+			MaxAgeDays: 7,
+		},
+		BreachCheck: breachCheckSettings{
+			Source:         BREACH_SOURCE_ONLINE,
+			BaseURL:        "https://api.pwnedpasswords.com",
+			TimeoutSeconds: 10,
+			Retries:        2,
+			Padding:        true,
+		},
+	}
+}
+
+// Returns the path of the settings file. It is located within the user's configuration
+// directory ($XDG_CONFIG_HOME/pwdman on Linux, %AppData%\pwdman on Windows).
+func settingsPath() (string, error) {
+	configHome, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configHome, "pwdman", "settings.json"), nil
+}
+
+// Reads the settings file into "userSettings". If the file does not exist,
+// the default settings are used.
+func loadSettings() error {
+	userSettings = defaultSettings()
+
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := readFile(path)
+	if err != nil || len(data) == 0 {
+		return err
+	}
+
+	if err = json.Unmarshal(data, &userSettings); err != nil {
+		return err
+	}
+
+	if err = userSettings.Generator.validate(); err != nil {
+		return fmt.Errorf("Invalid generator policy: %w", err)
+	}
+
+	if err = userSettings.BreachCheck.validate(); err != nil {
+		return fmt.Errorf("Invalid breach check: %w", err)
+	}
+
+	breachChecker = newBreachChecker(userSettings.BreachCheck)
+
+	return nil
+}
+
+// Writes "userSettings" to the settings file, creating it if necessary.
+func saveSettings() error {
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(userSettings, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, data)
+}
@@ -0,0 +1,1072 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// The patterns recognized by the strength estimator (see "estimateStrength()").
+const (
+	// A word of one of the dictionaries, possibly reversed or with l33t substitutions
+	PATTERN_DICTIONARY = "dictionary"
+	// A path of adjacent keys on a QWERTY keyboard (e.g. "qwerty" or "zaq1")
+	PATTERN_SPATIAL = "spatial"
+	// Characters with a constant distance (e.g. "abc" or "9753")
+	PATTERN_SEQUENCE = "sequence"
+	// Repeated characters or strings (e.g. "aaa" or "abcabc")
+	PATTERN_REPEAT = "repeat"
+	// A date or a recent year (e.g. "1990" or "24.12.1990")
+	PATTERN_DATE = "date"
+	// Characters which don't match any other pattern
+	PATTERN_BRUTEFORCE = "bruteforce"
+)
+
+// Only the first characters of a password are analyzed, longer passwords are strong anyway.
+const MAX_STRENGTH_LENGTH = 100
+
+// The constants of the guess estimation, mostly taken from zxcvbn (https://github.com/dropbox/zxcvbn)
+const (
+	// The number of guesses per character which doesn't match any pattern
+	BRUTEFORCE_CARDINALITY = 10
+	// The minimum number of guesses of a pattern which doesn't cover the whole password
+	MIN_SUBMATCH_GUESSES_SINGLE_CHAR = 10
+	MIN_SUBMATCH_GUESSES_MULTI_CHAR  = 50
+	// Penalizes splitting a password into many short patterns
+	MIN_GUESSES_BEFORE_GROWING_SEQUENCE = 10000
+	// The minimum distance of a year to the current year, as recent years are guessed first
+	MIN_YEAR_SPACE = 20
+	// The number of guesses of a word of the EFF wordlist whose words are equally likely
+	EFF_WORD_GUESSES = 7776 / 2
+	// The number of keys and the average number of neighbours of a key on a QWERTY keyboard
+	KEYBOARD_STARTING_POSITIONS = 94
+	KEYBOARD_AVERAGE_DEGREE     = 4.6
+)
+
+// Commonly used passwords, most common first (see "strengthDictionaries()").
+//
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// The rows of a QWERTY keyboard (unshifted and shifted) together with their horizontal offsets
+// in key widths, which determine the neighbours of a key (see "keyboardAdjacent()").
+var keyboardRows = []struct {
+	keys, shifted string
+	offset        float64
+}{
+	{"`1234567890-=", "~!@#$%^&*()_+", 0},
+	{"qwertyuiop[]\\", "QWERTYUIOP{}|", 1.5},
+	{"asdfghjkl;'", "ASDFGHJKL:\"", 1.75},
+	{"zxcvbnm,./", "ZXCVBNM<>?", 2.25},
+}
+
+// The position of a key on a QWERTY keyboard.
+type keyPosition struct {
+	row     int
+	x       float64
+	shifted bool
+}
+
+// Maps every character which can be typed on a QWERTY keyboard to its key.
+var keyboardPositions = sync.OnceValue(func() map[rune]keyPosition {
+	positions := map[rune]keyPosition{}
+
+	for row, r := range keyboardRows {
+		for i, c := range r.keys {
+			positions[c] = keyPosition{row, r.offset + float64(i), false}
+		}
+
+		for i, c := range r.shifted {
+			positions[c] = keyPosition{row, r.offset + float64(i), true}
+		}
+	}
+
+	return positions
+})
+
+// The letters which are commonly replaced by similar looking characters.
+var l33tTable = map[rune][]rune{
+	'4': {'a'}, '@': {'a'}, '8': {'b'}, '(': {'c'}, '{': {'c'}, '[': {'c'}, '<': {'c'},
+	'3': {'e'}, '6': {'g'}, '9': {'g'}, '1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'},
+	'0': {'o'}, '$': {'s'}, '5': {'s'}, '+': {'t'}, '7': {'t', 'l'}, '%': {'x'}, '2': {'z'},
+}
+
+// Dates with separators, e.g. "24.12.1990" or "1990-12-24".
+var dateWithSeparatorRegexp = regexp.MustCompile(`^(\d{1,4})([\s/\\_.-])(\d{1,2})([\s/\\_.-])(\d{1,4})$`)
+
+// A part of a password which matches one of the patterns (see "PATTERN_DICTIONARY" etc.).
+type strengthMatch struct {
+	Pattern string `json:"pattern"`
+	// The first and the last character of the match (inclusive)
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// The estimated number of guesses required to guess the match
+	Guesses float64 `json:"guesses"`
+
+	token []rune
+	// Dictionary matches
+	dictionary string
+	rank       int
+	reversed   bool
+	l33t       bool
+	// Spatial and sequence matches
+	turns     int
+	ascending bool
+	// Repeat matches
+	base []rune
+	// Date matches
+	year int
+}
+
+// The result of "estimateStrength()".
+type strength struct {
+	// 0 (too guessable) to 4 (very unguessable)
+	Score   int     `json:"score"`
+	Guesses float64 `json:"guesses"`
+	// The estimated time to guess the password in seconds for the attack scenarios (see "CRACK_*")
+	CrackTimes map[string]float64 `json:"crackTimes"`
+	// Explains what makes the password weak, empty if nothing stands out
+	Warning     string   `json:"warning"`
+	Suggestions []string `json:"suggestions"`
+	// The patterns the password has been split into, in the order of their position
+	Sequence []strengthMatch `json:"sequence"`
+}
+
+// The attack scenarios of the crack time estimation together with the guesses per second.
+const (
+	// An online attack against a service which limits the number of attempts
+	CRACK_ONLINE_THROTTLED = "onlineThrottled"
+	// An online attack against a service which doesn't limit the number of attempts
+	CRACK_ONLINE = "online"
+	// An offline attack against a slow hash like bcrypt or Argon2
+	CRACK_OFFLINE_SLOW = "offlineSlow"
+	// An offline attack against a fast hash like SHA-1 using many GPUs
+	CRACK_OFFLINE_FAST = "offlineFast"
+)
+
+var guessesPerSecond = map[string]float64{
+	CRACK_ONLINE_THROTTLED: 100.0 / 3600,
+	CRACK_ONLINE:           10,
+	CRACK_OFFLINE_SLOW:     1e4,
+	CRACK_OFFLINE_FAST:     1e10,
+}
+
+// The names of the scores (see "strength.Score").
+var scoreNames = []string{"Very weak", "Weak", "Fair", "Strong", "Very strong"}
+
+// A node of a dictionary stored as a trie, which allows to follow several l33t substitutions at once.
+type trieNode struct {
+	children map[rune]*trieNode
+	// The rank of the word ending at this node, 0 if no word ends here
+	rank int
+}
+
+// A dictionary of the strength estimator. Words with a lower rank are guessed first.
+type strengthDictionary struct {
+	name string
+	root *trieNode
+	// If set, every word requires this number of guesses regardless of its rank
+	guesses float64
+}
+
+// Returns a dictionary containing the given words, ranked by their order.
+func newStrengthDictionary(name string, words []string) strengthDictionary {
+	root := &trieNode{children: map[rune]*trieNode{}}
+
+	for rank, word := range words {
+		node := root
+
+		for _, r := range strings.ToLower(word) {
+			child, ok := node.children[r]
+			if !ok {
+				child = &trieNode{children: map[rune]*trieNode{}}
+				node.children[r] = child
+			}
+
+			node = child
+		}
+
+		if node.rank == 0 {
+			node.rank = rank + 1
+		}
+	}
+
+	return strengthDictionary{name: name, root: root}
+}
+
+// Returns the built-in dictionaries: common passwords and the words of the EFF wordlist.
+var strengthDictionaries = sync.OnceValue(func() []strengthDictionary {
+	passwords := []string{}
+
+	for line := range strings.Lines(commonPasswordsFile) {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			passwords = append(passwords, line)
+		}
+	}
+
+	words := newStrengthDictionary("words", effWordlist())
+	words.guesses = EFF_WORD_GUESSES
+
+	return []strengthDictionary{newStrengthDictionary("passwords", passwords), words}
+})
+
+// Estimates how many guesses an attacker needs to guess the given password, similar to zxcvbn.
+// The password is split into the sequence of patterns which is the easiest to guess. The given
+// inputs (e.g. the service and the user of the account) are treated as an additional dictionary,
+// as attackers are likely to try them first.
+func estimateStrength(pw string, inputs ...string) strength {
+	runes := []rune(pw)
+	runes = runes[:min(len(runes), MAX_STRENGTH_LENGTH)]
+
+	words := []string{}
+
+	for _, input := range inputs {
+		for word := range strings.FieldsFuncSeq(input, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+			if len(word) >= 3 {
+				words = append(words, word)
+			}
+		}
+	}
+
+	dictionaries := strengthDictionaries()
+
+	if len(words) > 0 {
+		dictionaries = append(slices.Clone(dictionaries), newStrengthDictionary("inputs", words))
+	}
+
+	matches := findMatches(runes, dictionaries)
+	sequence, guesses := mostGuessableSequence(runes, matches)
+
+	s := strength{
+		Score:      scoreOf(guesses),
+		Guesses:    guesses,
+		CrackTimes: map[string]float64{},
+		Sequence:   sequence,
+	}
+
+	for scenario, rate := range guessesPerSecond {
+		s.CrackTimes[scenario] = guesses / rate
+	}
+
+	s.Warning, s.Suggestions = strengthFeedback(s)
+
+	return s
+}
+
+// Returns all matches of all patterns within the given password.
+func findMatches(runes []rune, dictionaries []strengthDictionary) []strengthMatch {
+	matches := []strengthMatch{}
+
+	for _, d := range dictionaries {
+		matches = append(matches, dictionaryMatches(runes, d)...)
+	}
+
+	matches = append(matches, spatialMatches(runes)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, dateMatches(runes)...)
+	matches = append(matches, repeatMatches(runes, dictionaries)...)
+
+	return matches
+}
+
+// Returns the words of the given dictionary within the password, including reversed words
+// and words containing l33t substitutions (e.g. "p@ssw0rd").
+func dictionaryMatches(runes []rune, d strengthDictionary) []strengthMatch {
+	n := len(runes)
+	matches := trieMatches(runes, d)
+
+	reversed := slices.Clone(runes)
+	slices.Reverse(reversed)
+
+	for _, m := range trieMatches(reversed, d) {
+		m.Start, m.End = n-1-m.End, n-1-m.Start
+		m.token = runes[m.Start : m.End+1]
+		m.reversed = true
+
+		// Palindromes are found in both directions
+		if !slices.ContainsFunc(matches, func(f strengthMatch) bool {
+			return f.Start == m.Start && f.End == m.End && f.rank == m.rank
+		}) {
+			matches = append(matches, m)
+		}
+	}
+
+	for i := range matches {
+		matches[i].Guesses = dictionaryGuesses(matches[i], d)
+	}
+
+	return matches
+}
+
+// Returns the words of the given dictionary within the password by walking the trie of the
+// dictionary from every position of the password, following l33t substitutions as well.
+func trieMatches(runes []rune, d strengthDictionary) []strengthMatch {
+	matches := []strengthMatch{}
+
+	var walk func(node *trieNode, start, pos int, l33t bool)
+	walk = func(node *trieNode, start, pos int, l33t bool) {
+		if node.rank > 0 && pos-start >= 3 {
+			matches = append(matches, strengthMatch{
+				Pattern:    PATTERN_DICTIONARY,
+				Start:      start,
+				End:        pos - 1,
+				token:      runes[start:pos],
+				dictionary: d.name,
+				rank:       node.rank,
+				l33t:       l33t,
+			})
+		}
+
+		if pos == len(runes) {
+			return
+		}
+
+		r := unicode.ToLower(runes[pos])
+
+		if child, ok := node.children[r]; ok {
+			walk(child, start, pos+1, l33t)
+		}
+
+		for _, sub := range l33tTable[r] {
+			if child, ok := node.children[sub]; ok {
+				walk(child, start, pos+1, true)
+			}
+		}
+	}
+
+	for start := range runes {
+		walk(d.root, start, start, false)
+	}
+
+	return matches
+}
+
+// Returns the number of guesses of a dictionary match: the rank of the word multiplied by the
+// possible variations of its capitalization and of its l33t substitutions.
+func dictionaryGuesses(m strengthMatch, d strengthDictionary) float64 {
+	guesses := float64(m.rank)
+
+	if d.guesses > 0 {
+		guesses = d.guesses
+	}
+
+	guesses *= upperVariations(m.token)
+
+	if m.l33t {
+		guesses *= l33tVariations(m.token)
+	}
+
+	if m.reversed {
+		guesses *= 2
+	}
+
+	return guesses
+}
+
+// Returns the number of ways the letters of the given token could have been capitalized
+// as often as they are. Only the first, the last or all letters being uppercase counts as 2.
+func upperVariations(token []rune) float64 {
+	upper, lower := 0, 0
+
+	for _, r := range token {
+		switch {
+
+		case unicode.IsUpper(r):
+			upper++
+
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+
+	switch {
+
+	case upper == 0:
+		return 1
+
+	case lower == 0, upper == 1 && (unicode.IsUpper(token[0]) || unicode.IsUpper(token[len(token)-1])):
+		return 2
+	}
+
+	variations := 0.0
+
+	for i := 1; i <= min(upper, lower); i++ {
+		variations += binomial(upper+lower, i)
+	}
+
+	return variations
+}
+
+// Returns the number of ways the characters of the given token could have been substituted
+// (see "l33tTable") as often as they are.
+func l33tVariations(token []rune) float64 {
+	variations := 1.0
+	lowered := []rune(strings.ToLower(string(token)))
+
+	for sub, letters := range l33tTable {
+		subbed := strings.Count(string(lowered), string(sub))
+		if subbed == 0 {
+			continue
+		}
+
+		unsubbed := 0
+
+		for _, l := range letters {
+			unsubbed += strings.Count(string(lowered), string(l))
+		}
+
+		if unsubbed == 0 {
+			variations *= 2
+			continue
+		}
+
+		possibilities := 0.0
+
+		for i := 1; i <= min(subbed, unsubbed); i++ {
+			possibilities += binomial(subbed+unsubbed, i)
+		}
+
+		variations *= possibilities
+	}
+
+	return variations
+}
+
+// Returns the binomial coefficient "n choose k".
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+
+	result := 1.0
+
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+
+	return result
+}
+
+// Returns whether the given keys are next to each other on a QWERTY keyboard.
+func keyboardAdjacent(a, b keyPosition) bool {
+	switch a.row - b.row {
+
+	case 0:
+		return math.Abs(a.x-b.x) == 1
+
+	case -1, 1:
+		return math.Abs(a.x-b.x) <= 0.75
+	}
+
+	return false
+}
+
+// Returns the paths of at least 3 adjacent keys within the password (e.g. "qwerty" or "zaq1").
+func spatialMatches(runes []rune) []strengthMatch {
+	matches := []strengthMatch{}
+	positions := keyboardPositions()
+
+	for i := 0; i < len(runes); {
+		j, turns, direction := i, 0, [2]float64{}
+
+		for j+1 < len(runes) {
+			a, okA := positions[runes[j]]
+			b, okB := positions[runes[j+1]]
+
+			if !okA || !okB || !keyboardAdjacent(a, b) {
+				break
+			}
+
+			if d := [2]float64{float64(b.row - a.row), b.x - a.x}; d != direction {
+				turns++
+				direction = d
+			}
+
+			j++
+		}
+
+		if j-i+1 < 3 {
+			i++
+			continue
+		}
+
+		m := strengthMatch{Pattern: PATTERN_SPATIAL, Start: i, End: j, token: runes[i : j+1], turns: turns}
+		m.Guesses = spatialGuesses(m)
+		matches = append(matches, m)
+
+		i = j + 1
+	}
+
+	return matches
+}
+
+// Returns the number of guesses of a spatial match, which depends on its length and its turns.
+func spatialGuesses(m strengthMatch) float64 {
+	guesses := 0.0
+
+	for i := 2; i <= len(m.token); i++ {
+		for j := 1; j <= min(m.turns, i-1); j++ {
+			guesses += binomial(i-1, j-1) * KEYBOARD_STARTING_POSITIONS * math.Pow(KEYBOARD_AVERAGE_DEGREE, float64(j))
+		}
+	}
+
+	shifted := 0
+
+	for _, r := range m.token {
+		if keyboardPositions()[r].shifted {
+			shifted++
+		}
+	}
+
+	unshifted := len(m.token) - shifted
+
+	switch {
+
+	case shifted == 0:
+
+	case unshifted == 0:
+		guesses *= 2
+
+	default:
+		variations := 0.0
+
+		for i := 1; i <= min(shifted, unshifted); i++ {
+			variations += binomial(shifted+unshifted, i)
+		}
+
+		guesses *= variations
+	}
+
+	return guesses
+}
+
+// Returns the class of characters a sequence may consist of: lowercase letters, uppercase
+// letters or digits. Returns 0 for other characters.
+func sequenceClass(r rune) int {
+	switch {
+
+	case r >= 'a' && r <= 'z':
+		return 1
+
+	case r >= 'A' && r <= 'Z':
+		return 2
+
+	case r >= '0' && r <= '9':
+		return 3
+	}
+
+	return 0
+}
+
+// Returns the sequences of at least 3 characters of the same class with a constant distance
+// of up to 5 within the password (e.g. "abc", "9753" or "ACEG").
+func sequenceMatches(runes []rune) []strengthMatch {
+	matches := []strengthMatch{}
+
+	for i := 0; i+2 < len(runes); {
+		class, delta := sequenceClass(runes[i]), runes[i+1]-runes[i]
+		j := i + 1
+
+		for class != 0 && delta != 0 && max(delta, -delta) <= 5 && j < len(runes) &&
+			sequenceClass(runes[j]) == class && runes[j]-runes[j-1] == delta {
+			j++
+		}
+
+		if j-i < 3 {
+			i++
+			continue
+		}
+
+		m := strengthMatch{Pattern: PATTERN_SEQUENCE, Start: i, End: j - 1, token: runes[i:j], ascending: delta > 0}
+
+		base := 26.0
+
+		switch {
+
+		case strings.ContainsRune("aAzZ019", m.token[0]):
+			base = 4
+
+		case class == 3:
+			base = 10
+		}
+
+		if !m.ascending {
+			base *= 2
+		}
+
+		m.Guesses = base * float64(len(m.token))
+		matches = append(matches, m)
+
+		i = j
+	}
+
+	return matches
+}
+
+// Returns the number of guesses of the given year, as recent years are guessed first.
+func yearGuesses(year int) float64 {
+	now := time.Now().Year()
+
+	return float64(max(year-now, now-year, MIN_YEAR_SPACE))
+}
+
+// Returns the year of the date formed by the given digits (e.g. "24121990" or "901224") or 0 if
+// they don't form a valid date. Of several possible dates, the one closest to today is returned.
+func parseDate(digits string) int {
+	best := 0
+
+	for _, yearLength := range []int{4, 2} {
+		rest := len(digits) - yearLength
+		if rest < 2 || rest > 4 {
+			continue
+		}
+
+		for _, yearFirst := range []bool{true, false} {
+			yearDigits, dayMonth := digits[rest:], digits[:rest]
+
+			if yearFirst {
+				yearDigits, dayMonth = digits[:yearLength], digits[yearLength:]
+			}
+
+			year := parseYear(yearDigits)
+			if year == 0 {
+				continue
+			}
+
+			for split := 1; split < len(dayMonth); split++ {
+				a, _ := strconv.Atoi(dayMonth[:split])
+				b, _ := strconv.Atoi(dayMonth[split:])
+
+				if split > 2 || len(dayMonth)-split > 2 || !validDayMonth(a, b) && !validDayMonth(b, a) {
+					continue
+				}
+
+				if best == 0 || yearGuesses(year) < yearGuesses(best) {
+					best = year
+				}
+			}
+		}
+	}
+
+	return best
+}
+
+// Returns the year given by 2 or 4 digits or 0 if it is outside of the range of plausible years.
+func parseYear(digits string) int {
+	year, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0
+	}
+
+	switch {
+
+	case len(digits) == 2 && year > 50:
+		return 1900 + year
+
+	case len(digits) == 2:
+		return 2000 + year
+
+	case year >= 1000 && year <= 2050:
+		return year
+	}
+
+	return 0
+}
+
+// Returns whether the given numbers are a valid day and month.
+func validDayMonth(day, month int) bool {
+	return day >= 1 && day <= 31 && month >= 1 && month <= 12
+}
+
+// Returns the year of the date given by three groups of digits with the year either first
+// (e.g. "1990-12-24") or last (e.g. "24.12.1990"), or 0 if they don't form a valid date.
+func separatedDate(first, middle, last string) int {
+	for _, groups := range [][3]string{{first, middle, last}, {last, first, middle}} {
+		year, dayMonth := groups[0], groups[1:]
+
+		if len(year) != 2 && len(year) != 4 || len(dayMonth[0]) > 2 || len(dayMonth[1]) > 2 {
+			continue
+		}
+
+		a, _ := strconv.Atoi(dayMonth[0])
+		b, _ := strconv.Atoi(dayMonth[1])
+
+		if y := parseYear(year); y != 0 && (validDayMonth(a, b) || validDayMonth(b, a)) {
+			return y
+		}
+	}
+
+	return 0
+}
+
+// Returns the dates (e.g. "24.12.1990" or "901224") and recent years (e.g. "1990") within the password.
+func dateMatches(runes []rune) []strengthMatch {
+	matches := []strengthMatch{}
+
+	for i := range runes {
+		for j := i + 4; j <= min(i+10, len(runes)); j++ {
+			token := string(runes[i:j])
+			year, separated := 0, false
+
+			if groups := dateWithSeparatorRegexp.FindStringSubmatch(token); groups != nil {
+				if groups[2] != groups[4] {
+					continue
+				}
+
+				separated = true
+				year = separatedDate(groups[1], groups[3], groups[5])
+			} else if strings.Trim(token, DIGIT_CHARS) == "" {
+				if len(token) == 4 {
+					if y, _ := strconv.Atoi(token); y >= 1900 && y <= 2050 {
+						m := strengthMatch{Pattern: PATTERN_DATE, Start: i, End: j - 1, token: runes[i:j], year: y}
+						m.Guesses = yearGuesses(y)
+						matches = append(matches, m)
+					}
+				}
+
+				year = parseDate(token)
+			}
+
+			if year == 0 {
+				continue
+			}
+
+			m := strengthMatch{Pattern: PATTERN_DATE, Start: i, End: j - 1, token: runes[i:j], year: year}
+			m.Guesses = yearGuesses(year) * 365
+
+			if separated {
+				m.Guesses *= 4
+			}
+
+			matches = append(matches, m)
+		}
+	}
+
+	return matches
+}
+
+// Returns the repeated characters or strings within the password (e.g. "aaa" or "abcabc").
+// The guesses are those of the repeated string multiplied by the number of repetitions.
+func repeatMatches(runes []rune, dictionaries []strengthDictionary) []strengthMatch {
+	matches := []strengthMatch{}
+
+	for i := 0; i+1 < len(runes); {
+		unit, count := 0, 0
+
+		for u := 1; i+2*u <= len(runes); u++ {
+			k := 1
+
+			for i+(k+1)*u <= len(runes) && slices.Equal(runes[i+k*u:i+(k+1)*u], runes[i:i+u]) {
+				k++
+			}
+
+			if k >= 2 && u*k > unit*count {
+				unit, count = u, k
+			}
+		}
+
+		if count == 0 {
+			i++
+			continue
+		}
+
+		m := strengthMatch{
+			Pattern: PATTERN_REPEAT,
+			Start:   i,
+			End:     i + unit*count - 1,
+			token:   runes[i : i+unit*count],
+			base:    runes[i : i+unit],
+		}
+
+		_, baseGuesses := mostGuessableSequence(m.base, findMatches(m.base, dictionaries))
+		m.Guesses = baseGuesses * float64(count)
+		matches = append(matches, m)
+
+		i += unit * count
+	}
+
+	return matches
+}
+
+// Returns the sequence of non-overlapping matches covering the whole password which requires the
+// fewest guesses, together with the guesses. Characters which aren't covered by any of the given
+// matches are covered by bruteforce matches. Like zxcvbn, a sequence of l matches requires
+// l! * (the product of the guesses of the matches) + 10000^(l-1) guesses, which penalizes
+// splitting the password into many short patterns.
+func mostGuessableSequence(runes []rune, matches []strengthMatch) ([]strengthMatch, float64) {
+	n := len(runes)
+
+	if n == 0 {
+		return []strengthMatch{}, 1
+	}
+
+	// For every position k and number of matches l: the last match and the guesses of the best
+	// sequence covering the password up to k
+	best := make([]map[int]strengthMatch, n)
+	product := make([]map[int]float64, n)
+	total := make([]map[int]float64, n)
+
+	for k := range n {
+		best[k], product[k], total[k] = map[int]strengthMatch{}, map[int]float64{}, map[int]float64{}
+	}
+
+	update := func(m strengthMatch, l int) {
+		k := m.End
+		pi := guessesOf(m, n)
+
+		if l > 1 {
+			pi *= product[m.Start-1][l-1]
+		}
+
+		g := factorial(l)*pi + math.Pow(MIN_GUESSES_BEFORE_GROWING_SEQUENCE, float64(l-1))
+
+		for other, otherG := range total[k] {
+			if other <= l && otherG <= g {
+				return
+			}
+		}
+
+		best[k][l], product[k][l], total[k][l] = m, pi, g
+	}
+
+	bruteforce := func(i, j int) strengthMatch {
+		return strengthMatch{Pattern: PATTERN_BRUTEFORCE, Start: i, End: j, token: runes[i : j+1]}
+	}
+
+	for k := range n {
+		for _, m := range matches {
+			if m.End != k {
+				continue
+			}
+
+			if m.Start == 0 {
+				update(m, 1)
+				continue
+			}
+
+			for l := range best[m.Start-1] {
+				update(m, l+1)
+			}
+		}
+
+		update(bruteforce(0, k), 1)
+
+		for i := 1; i <= k; i++ {
+			for l, last := range best[i-1] {
+				// Consecutive bruteforce matches are covered by a single longer one
+				if last.Pattern != PATTERN_BRUTEFORCE {
+					update(bruteforce(i, k), l+1)
+				}
+			}
+		}
+	}
+
+	l, guesses := 0, math.Inf(1)
+
+	for candidate, g := range total[n-1] {
+		if g < guesses || g == guesses && candidate < l {
+			l, guesses = candidate, g
+		}
+	}
+
+	sequence := []strengthMatch{}
+
+	for k := n - 1; k >= 0; l-- {
+		m := best[k][l]
+		m.Guesses = guessesOf(m, n)
+		sequence = append(sequence, m)
+		k = m.Start - 1
+	}
+
+	slices.Reverse(sequence)
+
+	return sequence, guesses
+}
+
+// Returns the guesses of the given match within a password of the given length. Matches which
+// don't cover the whole password require a minimum number of guesses.
+func guessesOf(m strengthMatch, n int) float64 {
+	guesses := m.Guesses
+
+	if m.Pattern == PATTERN_BRUTEFORCE {
+		guesses = math.Pow(BRUTEFORCE_CARDINALITY, float64(len(m.token)))
+	}
+
+	if len(m.token) == n {
+		return max(guesses, 1)
+	}
+
+	if len(m.token) == 1 {
+		return max(guesses, MIN_SUBMATCH_GUESSES_SINGLE_CHAR+1)
+	}
+
+	return max(guesses, MIN_SUBMATCH_GUESSES_MULTI_CHAR+1)
+}
+
+// Returns n!.
+func factorial(n int) float64 {
+	result := 1.0
+
+	for i := 2; i <= n; i++ {
+		result *= float64(i)
+	}
+
+	return result
+}
+
+// Returns the score of the given number of guesses (see "strength.Score").
+func scoreOf(guesses float64) int {
+	for score, threshold := range []float64{1e3, 1e6, 1e8, 1e10} {
+		if guesses < threshold+5 {
+			return score
+		}
+	}
+
+	return 4
+}
+
+// Returns a warning explaining the weakest pattern of a weak password and suggestions how to improve it.
+func strengthFeedback(s strength) (string, []string) {
+	if len(s.Sequence) == 0 {
+		return "", []string{"Use a few words, avoid common phrases", "No need for symbols, digits or uppercase letters"}
+	}
+
+	if s.Score > 2 {
+		return "", []string{}
+	}
+
+	suggestions := []string{"Add another word or two, uncommon words are better"}
+
+	longest := s.Sequence[0]
+
+	for _, m := range s.Sequence[1:] {
+		if len(m.token) > len(longest.token) {
+			longest = m
+		}
+	}
+
+	warning := ""
+
+	switch longest.Pattern {
+
+	case PATTERN_DICTIONARY:
+		switch {
+
+		case longest.dictionary == "passwords" && longest.rank <= 10 && !longest.l33t && !longest.reversed:
+			warning = "This is a top-10 common password"
+
+		case longest.dictionary == "passwords" && longest.rank <= 100 && !longest.l33t && !longest.reversed:
+			warning = "This is a top-100 common password"
+
+		case longest.dictionary == "passwords":
+			warning = "This is similar to a commonly used password"
+
+		case longest.dictionary == "inputs":
+			warning = "Passwords containing the service or the user name are easy to guess"
+
+		case len(s.Sequence) == 1:
+			warning = "A word by itself is easy to guess"
+		}
+
+		if token := string(longest.token); strings.ToUpper(token) == token && strings.ToLower(token) != token {
+			suggestions = append(suggestions, "All-uppercase is almost as easy to guess as all-lowercase")
+		} else if unicode.IsUpper(longest.token[0]) {
+			suggestions = append(suggestions, "Capitalization doesn't help very much")
+		}
+
+		if longest.reversed {
+			suggestions = append(suggestions, "Reversed words aren't much harder to guess")
+		}
+
+		if longest.l33t {
+			suggestions = append(suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much")
+		}
+
+	case PATTERN_SPATIAL:
+		warning = "Short keyboard patterns are easy to guess"
+
+		if longest.turns == 1 {
+			warning = "Straight rows of keys are easy to guess"
+		}
+
+		suggestions = append(suggestions, "Use a longer keyboard pattern with more turns")
+
+	case PATTERN_REPEAT:
+		warning = `Repeats like "abcabc" are only slightly harder to guess than "abc"`
+
+		if len(longest.base) == 1 {
+			warning = `Repeats like "aaa" are easy to guess`
+		}
+
+		suggestions = append(suggestions, "Avoid repeated words and characters")
+
+	case PATTERN_SEQUENCE:
+		warning = "Sequences like abc or 6543 are easy to guess"
+		suggestions = append(suggestions, "Avoid sequences")
+
+	case PATTERN_DATE:
+		warning = "Dates are often easy to guess"
+
+		if len(longest.token) == 4 {
+			warning = "Recent years are easy to guess"
+		}
+
+		suggestions = append(suggestions, "Avoid dates and years that are associated with you")
+	}
+
+	return warning, suggestions
+}
+
+// Returns a human-readable representation of the given crack time in seconds.
+func formatCrackTime(seconds float64) string {
+	units := []struct {
+		name    string
+		seconds float64
+	}{
+		{"year", 365 * 24 * 3600},
+		{"month", 31 * 24 * 3600},
+		{"day", 24 * 3600},
+		{"hour", 3600},
+		{"minute", 60},
+		{"second", 1},
+	}
+
+	switch {
+
+	case seconds < 1:
+		return "less than a second"
+
+	case seconds >= 100*units[0].seconds:
+		return "centuries"
+	}
+
+	for _, u := range units {
+		if seconds >= u.seconds {
+			n := int(math.Round(seconds / u.seconds))
+
+			if n == 1 {
+				return "1 " + u.name
+			}
+
+			return fmt.Sprintf("%d %ss", n, u.name)
+		}
+	}
+
+	return ""
+}
+
+// Returns a human-readable representation of the given number of guesses, e.g. "10^8".
+func formatGuesses(guesses float64) string {
+	if math.IsInf(guesses, 1) || guesses >= 1e300 {
+		return "10^300+"
+	}
+
+	return fmt.Sprintf("10^%.0f", math.Floor(math.Log10(max(guesses, 1))))
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// Returns the patterns of the given matches, e.g. ["dictionary", "date"].
+func matchPatterns(matches []strengthMatch) []string {
+	patterns := []string{}
+
+	for _, m := range matches {
+		patterns = append(patterns, m.Pattern)
+	}
+
+	return patterns
+}
+
+func TestEstimateStrength(t *testing.T) {
+	tests := []struct {
+		pw       string
+		score    int
+		patterns []string
+	}{
+		{"", 0, []string{}},
+		{"password", 0, []string{PATTERN_DICTIONARY}},
+		{"P@ssw0rd", 0, []string{PATTERN_DICTIONARY}},
+		{"drowssap", 0, []string{PATTERN_DICTIONARY}},
+		{"qwertyuiop", 0, []string{PATTERN_DICTIONARY}},
+		{"zxcvfdsa", 1, []string{PATTERN_SPATIAL}},
+		{"poiuytlkjh", 2, []string{PATTERN_SPATIAL, PATTERN_SPATIAL}},
+		{"abcdef", 0, []string{PATTERN_SEQUENCE}},
+		{"9876543", 0, []string{PATTERN_SEQUENCE}},
+		{"aaaaaa", 0, []string{PATTERN_REPEAT}},
+		{"abcabcabc", 0, []string{PATTERN_REPEAT}},
+		{"1987-05-12", 1, []string{PATTERN_DATE}},
+		{"24.12.1990", 1, []string{PATTERN_DATE}},
+		{"19870512", 1, []string{PATTERN_DATE}},
+		{"8x#Lq2!vR9@mWz4$kT7p", 4, []string{PATTERN_BRUTEFORCE}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pw, func(t *testing.T) {
+			s := estimateStrength(tt.pw)
+
+			if s.Score != tt.score || !slices.Equal(matchPatterns(s.Sequence), tt.patterns) {
+				t.Fatalf("estimateStrength(%q) = score %d, patterns %v, want score %d, patterns %v",
+					tt.pw, s.Score, matchPatterns(s.Sequence), tt.score, tt.patterns)
+			}
+
+			for scenario, rate := range guessesPerSecond {
+				if s.CrackTimes[scenario] != s.Guesses/rate {
+					t.Fatalf("Crack time %s = %v, want %v", scenario, s.CrackTimes[scenario], s.Guesses/rate)
+				}
+			}
+
+			if s.Score < 3 && len(s.Warning) == 0 && len(s.Suggestions) == 0 {
+				t.Fatalf("estimateStrength(%q) gives no feedback although the password is weak", tt.pw)
+			}
+		})
+	}
+}
+
+func TestEstimateStrengthVariations(t *testing.T) {
+	tests := []struct {
+		pw       string
+		reversed bool
+		l33t     bool
+	}{
+		{"password", false, false},
+		{"drowssap", true, false},
+		{"P@ssw0rd", false, true},
+	}
+
+	for _, tt := range tests {
+		m := estimateStrength(tt.pw).Sequence[0]
+
+		if m.reversed != tt.reversed || m.l33t != tt.l33t {
+			t.Fatalf("estimateStrength(%q) = reversed %v, l33t %v, want reversed %v, l33t %v", tt.pw, m.reversed, m.l33t, tt.reversed, tt.l33t)
+		}
+	}
+
+	// Variations of a word are harder to guess than the word itself
+	plain, l33t := estimateStrength("password").Guesses, estimateStrength("P@ssw0rd").Guesses
+
+	if l33t <= plain {
+		t.Fatalf("%q needs %v guesses, %q %v", "P@ssw0rd", l33t, "password", plain)
+	}
+}
+
+func TestEstimateStrengthInputs(t *testing.T) {
+	without, with := estimateStrength("github2024"), estimateStrength("github2024", "GitHub", "octocat")
+
+	if with.Guesses >= without.Guesses {
+		t.Fatalf("The inputs didn't lower the guesses: %v with, %v without", with.Guesses, without.Guesses)
+	}
+
+	if m := with.Sequence[0]; m.Pattern != PATTERN_DICTIONARY || m.dictionary != "inputs" {
+		t.Fatalf("estimateStrength() matched %q as %s (%s), want the inputs dictionary", string(m.token), m.Pattern, m.dictionary)
+	}
+}
+
+func TestEstimateStrengthGenerated(t *testing.T) {
+	policy := defaultPwPolicy()
+	policy.MinLength, policy.MaxLength = 20, 20
+
+	for range 10 {
+		pw, err := generatePwInternal(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s := estimateStrength(string(*pw)); s.Score != 4 {
+			t.Fatalf("estimateStrength() of a generated password = score %d, want 4", s.Score)
+		}
+
+		zero(pw)
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	if m := sequenceMatches([]rune("9876543")); len(m) != 1 || string(m[0].token) != "9876543" || m[0].ascending {
+		t.Fatalf("sequenceMatches() = %+v, want a descending sequence", m)
+	}
+
+	if m := spatialMatches([]rune("zxcvfdsa")); len(m) != 1 || string(m[0].token) != "zxcvfdsa" || m[0].turns < 2 {
+		t.Fatalf("spatialMatches() = %+v, want a spatial match with turns", m)
+	}
+
+	dates := dateMatches([]rune("24.12.1990"))
+
+	if !slices.ContainsFunc(dates, func(m strengthMatch) bool { return string(m.token) == "24.12.1990" && m.year == 1990 }) {
+		t.Fatalf("dateMatches() = %+v, want 24.12.1990", dates)
+	}
+}
+
+func TestScoreOf(t *testing.T) {
+	tests := []struct {
+		guesses float64
+		score   int
+	}{
+		{1, 0},
+		{1e3 + 4, 0},
+		{1e3 + 5, 1},
+		{1e6 + 4, 1},
+		{1e6 + 5, 2},
+		{1e8 + 5, 3},
+		{1e10 + 4, 3},
+		{1e10 + 5, 4},
+		{1e20, 4},
+	}
+
+	for _, tt := range tests {
+		if got := scoreOf(tt.guesses); got != tt.score {
+			t.Fatalf("scoreOf(%v) = %d, want %d", tt.guesses, got, tt.score)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// An account which has been deleted and can be restored until the trash is purged.
+type trashedAccount struct {
+	Account account   `json:"account"`
+	Deleted time.Time `json:"deleted"`
+}
+
+// Returns the path of the trash of the active vault. It is located next to the vault and
+// encrypted using the same key.
+func trashPath() string {
+	return vaultPath + ".trash"
+}
+
+// Returns the time at which the given deleted account is purged from the trash.
+// The time is zero if deleted accounts are kept indefinitely.
+func (t trashedAccount) expires() time.Time {
+	if userSettings.Trash.RetentionDays <= 0 {
+		return time.Time{}
+	}
+
+	return t.Deleted.AddDate(0, 0, userSettings.Trash.RetentionDays)
+}
+
+// Returns whether the retention period of the given deleted account has elapsed.
+func (t trashedAccount) expired() bool {
+	expires := t.expires()
+
+	return !expires.IsZero() && time.Now().After(expires)
+}
+
+// Decrypts the trash of the active vault and returns the deleted accounts, oldest first.
+// A trash which doesn't exist is treated as an empty trash.
+func readTrash() ([]trashedAccount, error) {
+	trash := []trashedAccount{}
+
+	if err := readEncryptedJSON(trashPath(), &trash); err != nil {
+		return nil, err
+	}
+
+	return trash, nil
+}
+
+// Encrypts the given deleted accounts and writes them to the trash of the active vault.
+func writeTrash(trash []trashedAccount) error {
+	return writeEncryptedJSON(trashPath(), trash)
+}
+
+// Moves the given account into the trash of the active vault. The account itself has to be
+// removed from the vault afterwards, so that it is never lost if the trash can't be written.
+func trashAccount(a account) error {
+	trash, err := readTrash()
+	if err != nil {
+		return err
+	}
+
+	return writeTrash(append(trash, trashedAccount{Account: a, Deleted: now()}))
+}
+
+// Removes the account with the given ID from the trash of the active vault and returns it.
+// The account has to be added to the vault beforehand, so that it is never lost.
+// The returned error wraps ErrNotFound if there is no such account in the trash.
+func untrashAccount(id string) (account, error) {
+	trash, err := readTrash()
+	if err != nil {
+		return account{}, err
+	}
+
+	index := slices.IndexFunc(trash, func(t trashedAccount) bool {
+		return t.Account.ID == id
+	})
+
+	if index < 0 {
+		return account{}, fmt.Errorf("%w in the trash: %q", ErrNotFound, id)
+	}
+
+	a := trash[index].Account
+
+	return a, writeTrash(slices.Delete(trash, index, index+1))
+}
+
+// Removes all accounts whose retention period has elapsed from the trash of the active vault
+// (see "trashSettings") and records them in the journal.
+func purgeTrash() error {
+	trash, err := readTrash()
+	if err != nil {
+		return err
+	}
+
+	entries := []journalEntry{}
+
+	for _, t := range trash {
+		if t.expired() {
+			entries = append(entries, newJournalEntry(&t.Account, nil, "Purged from the trash"))
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if err := writeTrash(slices.DeleteFunc(trash, trashedAccount.expired)); err != nil {
+		return err
+	}
+
+	return appendJournal(entries...)
+}
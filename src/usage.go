@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Returns the path of the usage file of the active vault, which contains the time the password
+// of every account has last been copied (see "account.LastUsed"). It is located next to the vault
+// and encrypted using the same key. Keeping the usage apart from the vault means that copying a
+// password neither rewrites the vault nor replaces its .bak file or its snapshots.
+func usagePath() string {
+	return vaultPath + ".usage"
+}
+
+// The time an account has last been used as stored within the vault by older versions of PwdMan.
+type legacyUsage struct {
+	ID       string    `json:"id"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+// Sets the time the accounts have last been used according to the usage file of the active vault.
+// Older versions of PwdMan stored the time within the given decrypted vault data instead, such
+// times are moved into the usage file once and dropped from the vault by its next save (see
+// "account.LastUsed"). As the usage only affects the order of the accounts table, a usage file
+// which can't be read or written is ignored.
+func applyUsage(accounts []account, data []byte) {
+	usage := map[string]time.Time{}
+
+	if err := readEncryptedJSON(usagePath(), &usage); err != nil {
+		return
+	}
+
+	// Vaults which can only be decoded partially (see "ErrUndecodable") may not be decoded at all
+	legacy := []legacyUsage{}
+	json.Unmarshal(data, &legacy)
+
+	migrated := false
+
+	for _, l := range legacy {
+		if len(l.ID) > 0 && l.LastUsed.After(usage[l.ID]) {
+			usage[l.ID] = l.LastUsed
+			migrated = true
+		}
+	}
+
+	if migrated {
+		writeEncryptedJSON(usagePath(), usage)
+	}
+
+	for i := range accounts {
+		accounts[i].LastUsed = usage[accounts[i].ID]
+	}
+}
+
+// Encrypts the time the given accounts have last been used and writes it to the usage file
+// of the active vault. Used if nothing but the usage of the accounts changed, the vault itself
+// is left untouched.
+func saveUsageToDisk(accounts *[]account) error {
+	usage := map[string]time.Time{}
+
+	for _, a := range *accounts {
+		if !a.LastUsed.IsZero() {
+			usage[a.ID] = a.LastUsed
+		}
+	}
+
+	return writeEncryptedJSON(usagePath(), usage)
+}
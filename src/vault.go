@@ -0,0 +1,444 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// The current version of the vault file format. Every vault file written by PwdMan
+// starts with a header describing how its payload has been encrypted:
+//
+//	magic        4 bytes, "PWDM"
+//	version      1 byte
+//	kdf id       1 byte
+//	kdf params   Argon2id only: time (uint32), memory in KiB (uint32), threads (uint8)
+//	salt length  1 byte, followed by the salt
+//	cipher id    1 byte
+//	nonce length 1 byte, followed by the nonce
+//	ciphertext   the remaining bytes
+//
+// All integers are stored in big endian byte order. Files without the magic bytes
+// are considered to be legacy (headerless) files written by older versions of PwdMan.
+const VAULT_VERSION uint8 = 1
+
+// Identifiers of the key derivation functions which can be stored in a vault header.
+const (
+	KDF_NONE     uint8 = 0
+	KDF_ARGON2ID uint8 = 1
+)
+
+// Identifiers of the ciphers which can be stored in a vault header.
+const (
+	CIPHER_AES_256_GCM uint8 = 1
+	CIPHER_DPAPI       uint8 = 2
+)
+
+var VAULT_MAGIC = []byte("PWDM")
+
+// The header of a vault file. See "VAULT_VERSION" for a description of the layout.
+type vaultHeader struct {
+	Version    uint8
+	KDF        uint8
+	KDFTime    uint32
+	KDFMemory  uint32
+	KDFThreads uint8
+	Salt       []byte
+	Cipher     uint8
+	Nonce      []byte
+}
+
+// Returns whether the given data starts with the magic bytes of a vault file.
+func isFramedVault(data []byte) bool {
+	return bytes.HasPrefix(data, VAULT_MAGIC)
+}
+
+// Serializes the header into its binary representation.
+func (h *vaultHeader) marshal() []byte {
+	buf := bytes.NewBuffer(nil)
+
+	buf.Write(VAULT_MAGIC)
+	buf.WriteByte(h.Version)
+	buf.WriteByte(h.KDF)
+
+	if h.KDF == KDF_ARGON2ID {
+		binary.Write(buf, binary.BigEndian, h.KDFTime)
+		binary.Write(buf, binary.BigEndian, h.KDFMemory)
+		buf.WriteByte(h.KDFThreads)
+	}
+
+	buf.WriteByte(uint8(len(h.Salt)))
+	buf.Write(h.Salt)
+	buf.WriteByte(h.Cipher)
+	buf.WriteByte(uint8(len(h.Nonce)))
+	buf.Write(h.Nonce)
+
+	return buf.Bytes()
+}
+
+// Parses the header of a vault file. Returns the parsed header, the raw bytes of
+// the header (to be used as additional authenticated data) and the ciphertext.
+// The returned error wraps "ErrCorruptVault", "ErrUnsupportedVersion" or "ErrUnsupportedCipher".
+func parseVault(data []byte) (*vaultHeader, []byte, []byte, error) {
+	if !isFramedVault(data) {
+		return nil, nil, nil, fmt.Errorf("%w (the data is not a vault file)", ErrCorruptVault)
+	}
+
+	errTruncated := fmt.Errorf("%w (the header is truncated)", ErrCorruptVault)
+
+	r := bytes.NewReader(data[len(VAULT_MAGIC):])
+	h := vaultHeader{}
+
+	readBytes := func() []byte {
+		n, err := r.ReadByte()
+		if err != nil {
+			return nil
+		}
+
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil
+		}
+
+		return b
+	}
+
+	var err error
+
+	if h.Version, err = r.ReadByte(); err != nil {
+		return nil, nil, nil, errTruncated
+	}
+
+	if h.Version != VAULT_VERSION {
+		return nil, nil, nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, h.Version)
+	}
+
+	if h.KDF, err = r.ReadByte(); err != nil {
+		return nil, nil, nil, errTruncated
+	}
+
+	switch h.KDF {
+
+	case KDF_NONE:
+
+	case KDF_ARGON2ID:
+		if binary.Read(r, binary.BigEndian, &h.KDFTime) != nil ||
+			binary.Read(r, binary.BigEndian, &h.KDFMemory) != nil {
+			return nil, nil, nil, errTruncated
+		}
+
+		if h.KDFThreads, err = r.ReadByte(); err != nil {
+			return nil, nil, nil, errTruncated
+		}
+
+	default:
+		return nil, nil, nil, fmt.Errorf("%w (key derivation function %d)", ErrUnsupportedCipher, h.KDF)
+	}
+
+	if h.Salt = readBytes(); h.Salt == nil {
+		return nil, nil, nil, errTruncated
+	}
+
+	if h.Cipher, err = r.ReadByte(); err != nil {
+		return nil, nil, nil, errTruncated
+	}
+
+	if h.Nonce = readBytes(); h.Nonce == nil {
+		return nil, nil, nil, errTruncated
+	}
+
+	if err := h.validate(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	headerLen := len(data) - r.Len()
+
+	return &h, data[:headerLen], data[headerLen:], nil
+}
+
+// Returns an error wrapping "ErrCorruptVault" or "ErrUnsupportedCipher" if the header describes
+// parameters which PwdMan never writes, e.g. a salt or nonce of the wrong size or Argon2id
+// parameters which would crash or exhaust the machine (see "checkKDFParams()").
+func (h *vaultHeader) validate() error {
+	if h.KDF == KDF_ARGON2ID {
+		if err := checkKDFParams(h.KDFTime, h.KDFMemory, h.KDFThreads); err != nil {
+			return err
+		}
+
+		if len(h.Salt) < SALT_SIZE {
+			return fmt.Errorf("%w (the salt stored in the header is too short)", ErrCorruptVault)
+		}
+	} else if len(h.Salt) != 0 {
+		return fmt.Errorf("%w (the header contains a salt without a key derivation function)", ErrCorruptVault)
+	}
+
+	switch h.Cipher {
+
+	case CIPHER_AES_256_GCM:
+		if h.KDF != KDF_ARGON2ID {
+			return fmt.Errorf("%w (the portable backend requires Argon2id)", ErrUnsupportedCipher)
+		}
+
+		if len(h.Nonce) != GCM_NONCE_SIZE {
+			return fmt.Errorf("%w (the nonce stored in the header has an invalid size)", ErrCorruptVault)
+		}
+
+	case CIPHER_DPAPI:
+		if len(h.Nonce) != 0 {
+			return fmt.Errorf("%w (the header contains a nonce although DPAPI doesn't use one)", ErrCorruptVault)
+		}
+
+	default:
+		return fmt.Errorf("%w (cipher %d)", ErrUnsupportedCipher, h.Cipher)
+	}
+
+	return nil
+}
+
+// The available encryption backends. The native backend utilizes the DPAPI on Windows
+// and is equivalent to the portable backend on Linux. The portable backend derives the key
+// from the master password, so that the vault can be opened on both Windows and Linux.
+const (
+	BACKEND_NATIVE   = "native"
+	BACKEND_PORTABLE = "portable"
+)
+
+// The backend used by "encrypt()" when writing the vault.
+var backend = BACKEND_NATIVE
+
+// Returns the backend which has been used to write the given vault data.
+// Legacy (headerless) data and empty data are considered to be written by the native backend.
+func detectBackend(data []byte) string {
+	if !isFramedVault(data) {
+		return BACKEND_NATIVE
+	}
+
+	h, _, _, err := parseVault(data)
+	if err != nil || h.Cipher != CIPHER_AES_256_GCM {
+		return BACKEND_NATIVE
+	}
+
+	return BACKEND_PORTABLE
+}
+
+// Returns whether a master password is required to open the given vault data and to write
+// it using the currently selected backend.
+func needsMasterPw(data []byte) bool {
+	return backend == BACKEND_PORTABLE || requiresMasterPw(data)
+}
+
+// Returns whether a master password is required to open the given vault data.
+func requiresMasterPw(data []byte) bool {
+	if nativeUsesMasterPw {
+		return true
+	}
+
+	if !isFramedVault(data) {
+		return false
+	}
+
+	h, _, _, err := parseVault(data)
+
+	return err == nil && h.KDF != KDF_NONE
+}
+
+// Encrypts some data using the selected backend (see "backend") and returns a pointer pointing
+// to the encrypted byte slice which is prefixed with a vault header. Also zeros the given data.
+func encrypt(data []byte) (*[]byte, error) {
+	if backend == BACKEND_PORTABLE {
+		return portableEncrypt(data)
+	}
+
+	return nativeEncrypt(data)
+}
+
+// Decrypts some data and returns a pointer pointing to the decrypted byte slice.
+// Also zeros the given data. The backend is chosen based on the cipher stored in the
+// vault header, so vaults written by the portable backend can be opened on every platform.
+// The returned error wraps one of the errors defined in errors.go.
+func decrypt(data []byte) (*[]byte, error) {
+	if isFramedVault(data) {
+		h, header, encrypted, err := parseVault(data)
+		if err != nil {
+			zero(&data)
+			return nil, err
+		}
+
+		if h.Cipher == CIPHER_AES_256_GCM {
+			defer zero(&data)
+
+			return portableDecrypt(h, header, encrypted)
+		}
+	}
+
+	return nativeDecrypt(data)
+}
+
+// Decrypts the given file (e.g. the journal of the vault) using the key of the vault and decodes
+// the JSON stored within it into v. A file which doesn't exist is left undecoded.
+func readEncryptedJSON(path string, v any) error {
+	encryptedData, err := readFile(path)
+	if err != nil || len(encryptedData) == 0 {
+		return err
+	}
+
+	data, err := decrypt(encryptedData)
+	if err != nil {
+		return fmt.Errorf("Could not decrypt %s: %w", filepath.Base(path), err)
+	}
+
+	defer zero(data)
+
+	if err := json.Unmarshal(*data, v); err != nil {
+		return fmt.Errorf("%w (%s: %w)", ErrCorruptVault, filepath.Base(path), err)
+	}
+
+	return nil
+}
+
+// Encodes v as JSON, encrypts it using the key of the vault and writes it to the given file.
+func writeEncryptedJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	encryptedData, err := encrypt(data)
+	if err != nil {
+		return err
+	}
+
+	defer zero(encryptedData)
+
+	return writeFileAtomic(path, *encryptedData)
+}
+
+// A file belonging to the vault which is re-encrypted by "rekeyVault()".
+type rekeyedFile struct {
+	path string
+	// The encrypted content of the file, restored if not all files could be replaced
+	previous []byte
+	// The decrypted content of the file
+	data []byte
+	// The temporary file containing the re-encrypted content (see "stageFile()")
+	tmp string
+}
+
+// Re-encrypts the vault using the given master password. The vault is decrypted using the
+// current master password (or the legacy key), after which a new key is derived from the
+// new master password using a fresh salt and nonce and the vault is written back to disk.
+// All files belonging to the vault (its .bak file, journal, trash, audit cache and snapshots,
+// see "vaultFiles()") are re-encrypted as well, so that none of them can be decrypted using
+// the previous master password anymore.
+//
+// All files are re-encrypted into temporary files first and only replace the original files
+// once every one of them has been written. If a file can't be replaced, the files replaced so far
+// are restored and the previous master password stays active. Files which can't be decrypted
+// using the current master password are left untouched and returned, as they keep their previous key.
+func rekeyVault(newPw []byte) ([]string, error) {
+	accounts, err := getAllAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(*accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := readFile(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := vaultFiles(vaultName())
+	if err != nil {
+		return nil, err
+	}
+
+	files := []rekeyedFile{{path: vaultPath, previous: previous, data: data}}
+	skipped := []string{}
+
+	defer func() {
+		for i := range files {
+			zero(&files[i].previous)
+			zero(&files[i].data)
+
+			if len(files[i].tmp) > 0 {
+				os.Remove(files[i].tmp)
+			}
+		}
+	}()
+
+	for _, path := range paths[1:] {
+		encryptedData, err := readFile(path)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s (%v)", filepath.Base(path), err))
+			continue
+		} else if len(encryptedData) == 0 {
+			continue
+		}
+
+		data, err := decrypt(bytes.Clone(encryptedData))
+		if err != nil {
+			zero(&encryptedData)
+			skipped = append(skipped, fmt.Sprintf("%s (%v)", filepath.Base(path), err))
+
+			continue
+		}
+
+		files = append(files, rekeyedFile{path: path, previous: encryptedData, data: *data})
+	}
+
+	oldPw := masterPw
+	masterPw = newPw
+
+	if err := replaceRekeyedFiles(files); err != nil {
+		masterPw = oldPw
+		return nil, err
+	}
+
+	zero(&oldPw)
+
+	return skipped, nil
+}
+
+// Encrypts the given files using the current master password into temporary files and
+// replaces the original files afterwards. If a file can't be replaced, the previous content
+// of the files which have already been replaced is restored.
+func replaceRekeyedFiles(files []rekeyedFile) error {
+	for i := range files {
+		encryptedData, err := encrypt(files[i].data)
+		if err != nil {
+			return err
+		}
+
+		files[i].tmp, err = stageFile(files[i].path, *encryptedData)
+		zero(encryptedData)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, f := range files {
+		if err := replaceFile(f.tmp, f.path); err != nil {
+			for _, replaced := range files[:i] {
+				if restoreErr := writeFileAtomic(replaced.path, replaced.previous); restoreErr != nil {
+					err = errors.Join(err, fmt.Errorf("Could not restore %s: %w", filepath.Base(replaced.path), restoreErr))
+				}
+			}
+
+			return err
+		}
+
+		files[i].tmp = ""
+	}
+
+	return nil
+}
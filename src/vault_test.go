@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Returns the header written by the portable backend, see "portableEncrypt()".
+func portableHeader() vaultHeader {
+	return vaultHeader{
+		Version:    VAULT_VERSION,
+		KDF:        KDF_ARGON2ID,
+		KDFTime:    ARGON2_TIME,
+		KDFMemory:  ARGON2_MEMORY,
+		KDFThreads: ARGON2_THREADS,
+		Salt:       bytes.Repeat([]byte{1}, SALT_SIZE),
+		Cipher:     CIPHER_AES_256_GCM,
+		Nonce:      bytes.Repeat([]byte{2}, GCM_NONCE_SIZE),
+	}
+}
+
+func TestParseVault(t *testing.T) {
+	tests := []struct {
+		name string
+		// Modifies the portable header before it is marshaled
+		modify func(h *vaultHeader)
+		want   error
+	}{
+		{"portable", func(h *vaultHeader) {}, nil},
+		{"dpapi", func(h *vaultHeader) { *h = vaultHeader{Version: VAULT_VERSION, KDF: KDF_NONE, Cipher: CIPHER_DPAPI} }, nil},
+		{"unsupported version", func(h *vaultHeader) { h.Version = VAULT_VERSION + 1 }, ErrUnsupportedVersion},
+		{"unknown kdf", func(h *vaultHeader) { h.KDF = 7 }, ErrUnsupportedCipher},
+		{"unknown cipher", func(h *vaultHeader) { h.Cipher = 7 }, ErrUnsupportedCipher},
+		{"aes without kdf", func(h *vaultHeader) { h.KDF, h.Salt = KDF_NONE, nil }, ErrUnsupportedCipher},
+		{"zero threads", func(h *vaultHeader) { h.KDFThreads = 0 }, ErrCorruptVault},
+		{"too many threads", func(h *vaultHeader) { h.KDFThreads = ARGON2_MAX_THREADS + 1 }, ErrCorruptVault},
+		{"zero time", func(h *vaultHeader) { h.KDFTime = 0 }, ErrCorruptVault},
+		{"downgraded time", func(h *vaultHeader) { h.KDFTime = ARGON2_TIME - 1 }, ErrCorruptVault},
+		{"downgraded memory", func(h *vaultHeader) { h.KDFMemory = ARGON2_MEMORY / 2 }, ErrCorruptVault},
+		{"stronger parameters", func(h *vaultHeader) { h.KDFTime, h.KDFMemory, h.KDFThreads = ARGON2_MAX_TIME, ARGON2_MAX_MEMORY, 1 }, nil},
+		{"huge time", func(h *vaultHeader) { h.KDFTime = 1 << 31 }, ErrCorruptVault},
+		{"huge memory", func(h *vaultHeader) { h.KDFMemory = 0xFFFFFFFF }, ErrCorruptVault},
+		{"memory below threads", func(h *vaultHeader) { h.KDFMemory, h.KDFThreads = 8, 2 }, ErrCorruptVault},
+		{"short salt", func(h *vaultHeader) { h.Salt = h.Salt[:SALT_SIZE-1] }, ErrCorruptVault},
+		{"salt without kdf", func(h *vaultHeader) { h.KDF, h.Cipher, h.Nonce = KDF_NONE, CIPHER_DPAPI, nil }, ErrCorruptVault},
+		{"short nonce", func(h *vaultHeader) { h.Nonce = h.Nonce[:GCM_NONCE_SIZE-1] }, ErrCorruptVault},
+		{"long nonce", func(h *vaultHeader) { h.Nonce = append(h.Nonce, 0) }, ErrCorruptVault},
+		{"dpapi with nonce", func(h *vaultHeader) {
+			*h = vaultHeader{Version: VAULT_VERSION, KDF: KDF_NONE, Cipher: CIPHER_DPAPI, Nonce: []byte{1}}
+		}, ErrCorruptVault},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := portableHeader()
+			tt.modify(&h)
+
+			header := h.marshal()
+			data := append(bytes.Clone(header), "ciphertext"...)
+
+			parsed, rawHeader, encrypted, err := parseVault(data)
+
+			if !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+				t.Fatalf("parseVault() error = %v, want %v", err, tt.want)
+			}
+
+			if err == nil && (!bytes.Equal(rawHeader, header) || string(encrypted) != "ciphertext" || parsed.KDFMemory != h.KDFMemory) {
+				t.Fatalf("parseVault() = %+v, %x, %q", parsed, rawHeader, encrypted)
+			}
+		})
+	}
+}
+
+func TestParseVaultTruncated(t *testing.T) {
+	header := portableHeader()
+	data := header.marshal()
+
+	for n := range len(data) {
+		if _, _, _, err := parseVault(data[:n]); !errors.Is(err, ErrCorruptVault) {
+			t.Fatalf("parseVault() of %d header bytes: error = %v, want %v", n, err, ErrCorruptVault)
+		}
+	}
+}
+
+func TestDeriveKeyRejectsInvalidParams(t *testing.T) {
+	masterPw = []byte("master password")
+	defer func() { masterPw = nil }()
+
+	if _, err := deriveKey(make([]byte, SALT_SIZE), ARGON2_TIME, ARGON2_MEMORY, 0); !errors.Is(err, ErrCorruptVault) {
+		t.Fatalf("deriveKey() error = %v, want %v", err, ErrCorruptVault)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// The pattern every vault name has to match. As vault names never contain dots, files like
+// accounts.bak, temporary files and the backups directory are never mistaken for a vault.
+var vaultNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// The backend requested using the -backend flag. If empty, the backend of every
+// vault is detected when it is opened (see "selectVault()").
+var preferredBackend string
+
+// Returns the directory containing all named vaults, which is the directory of the active vault.
+func vaultsDir() string {
+	return filepath.Dir(vaultPath)
+}
+
+// Returns the name of the active vault.
+func vaultName() string {
+	return filepath.Base(vaultPath)
+}
+
+// Returns the path of the vault with the given name.
+func namedVaultPath(name string) string {
+	return filepath.Join(vaultsDir(), name)
+}
+
+// Returns an error if the given vault name is invalid.
+func checkVaultName(name string) error {
+	if !vaultNamePattern.MatchString(name) {
+		return errors.New("Vault names may only contain letters, digits, '-' and '_' (at most 64 characters)")
+	}
+
+	return nil
+}
+
+// Returns the names of all vaults within the vaults directory in alphabetical order. Only files
+// starting with the magic bytes of a vault (see "VAULT_MAGIC") are listed, so that unrelated files
+// which happen to be stored within the same directory are never shown, renamed or deleted.
+// Vaults written by older versions of PwdMan have no header, but are always named like
+// "LEGACY_VAULT_PATH", hence such a file is listed as well.
+func listVaults() ([]string, error) {
+	entries, err := os.ReadDir(vaultsDir())
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !vaultNamePattern.MatchString(entry.Name()) {
+			continue
+		}
+
+		if entry.Name() == LEGACY_VAULT_PATH || hasVaultMagic(filepath.Join(vaultsDir(), entry.Name())) {
+			names = append(names, entry.Name())
+		}
+	}
+
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// Returns whether the file at the given path starts with the magic bytes of a vault.
+func hasVaultMagic(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+
+	defer f.Close()
+
+	magic := make([]byte, len(VAULT_MAGIC))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false
+	}
+
+	return isFramedVault(magic)
+}
+
+// Makes the vault at the given path the active vault. The backend is taken from the -backend
+// flag or detected from the existing vault. Also zeros the master password of the previous vault.
+func selectVault(path string) {
+	if path != vaultPath {
+		zero(&masterPw)
+		masterPw = nil
+	}
+
+	vaultPath = path
+
+	if len(preferredBackend) > 0 {
+		backend = preferredBackend
+		return
+	}
+
+	// Errors are reported once the vault is loaded (see "getAllAccounts()")
+	encryptedData, _ := readFile(vaultPath)
+	backend = detectBackend(encryptedData)
+	zero(&encryptedData)
+}
+
+// Returns the paths of all files belonging to the vault with the given name
+// (the vault itself, its .bak file, its journal, its trash, its audit cache and its snapshots).
+func vaultFiles(name string) ([]string, error) {
+	path := namedVaultPath(name)
+	files := []string{path}
+
+	for _, suffix := range []string{".bak", ".journal", ".trash", ".audit", ".usage"} {
+		if _, err := os.Stat(path + suffix); err == nil {
+			files = append(files, path+suffix)
+		}
+	}
+
+	entries, err := os.ReadDir(backupsDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if rest, found := strings.CutPrefix(entry.Name(), name+"-"); found && isSnapshotTime(rest) {
+			files = append(files, filepath.Join(backupsDir(), entry.Name()))
+		}
+	}
+
+	return files, nil
+}
+
+// Renames the vault with the given name including all files belonging to it (see "vaultFiles()").
+// If the renamed vault is the active vault, the new path becomes the active path.
+func renameVault(oldName string, newName string) error {
+	if err := checkVaultName(newName); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(namedVaultPath(newName)); err == nil {
+		return fmt.Errorf("A vault named %q already exists", newName)
+	}
+
+	files, err := vaultFiles(oldName)
+	if err != nil {
+		return err
+	}
+
+	oldPrefix, newPrefix := namedVaultPath(oldName), namedVaultPath(newName)
+	oldSnapshotPrefix := filepath.Join(backupsDir(), oldName+"-")
+	newSnapshotPrefix := filepath.Join(backupsDir(), newName+"-")
+
+	for _, file := range files {
+		target := strings.Replace(file, oldPrefix, newPrefix, 1)
+
+		if strings.HasPrefix(file, oldSnapshotPrefix) {
+			target = newSnapshotPrefix + strings.TrimPrefix(file, oldSnapshotPrefix)
+		}
+
+		if err := os.Rename(file, target); err != nil {
+			return err
+		}
+	}
+
+	if vaultPath == oldPrefix {
+		vaultPath = newPrefix
+	}
+
+	return nil
+}
+
+// Deletes the vault with the given name including all files belonging to it (see "vaultFiles()").
+func deleteVault(name string) error {
+	files, err := vaultFiles(name)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Appends the given account to the vault with the given name which is protected by the given
+// master password (if any) and records it in the journal of that vault. The active vault is left untouched.
+func appendToVault(name string, pw []byte, a account) error {
+	prevPath, prevPw, prevBackend := vaultPath, masterPw, backend
+	note := fmt.Sprintf("Moved from vault %s", vaultName())
+
+	defer func() {
+		vaultPath, masterPw, backend = prevPath, prevPw, prevBackend
+	}()
+
+	vaultPath = namedVaultPath(name)
+	masterPw = pw
+
+	encryptedData, err := readFile(vaultPath)
+	if err != nil {
+		return err
+	}
+
+	backend = detectBackend(encryptedData)
+	zero(&encryptedData)
+
+	accounts, err := getAllAccounts()
+	if err != nil {
+		return err
+	}
+
+	*accounts = append(*accounts, a)
+
+	if err := saveAndJournal(accounts, newJournalEntry(nil, &a, note)); err != nil {
+		return err
+	}
+
+	// The usage only affects the order of the accounts table, hence errors are ignored
+	saveUsageToDisk(accounts)
+
+	return nil
+}
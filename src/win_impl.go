@@ -3,45 +3,72 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/billgraziano/dpapi"
 )
 
-// Encrypts some data and returns a pointer pointing to the encrypted byte slice.
+// On Windows, the native backend utilizes the DPAPI which is bound to the user's
+// Windows account. Therefore, no master password is required.
+const nativeUsesMasterPw = false
+
+// Encrypts some data using the native backend and returns a pointer pointing to the
+// encrypted byte slice which is prefixed with a vault header (see "VAULT_VERSION").
 // Also zeros the given data.
 // Note: On Windows, this function will benefit from Windows's [Data Protection API (DPAPI)]
-// by using [billgraziano]'s [Go wrapper]. On Linux, this function will utilize the
-// symmetric [AES-GCM] cipher. The AES key will be the SHA-256 hash of the data stored in
-// /etc/machine-id. This is implemented using the Go packages [crypto/aes] and [crypto/cipher].
+// by using [billgraziano]'s [Go wrapper]. On Linux, this function is equivalent to
+// "portableEncrypt()".
 //
 // [Data Protection API (DPAPI)]: https://wikipedia.org/wiki/Data_Protection_API
 // [billgraziano]: https://github.com/billgraziano
 // [Go wrapper]: https://pkg.go.dev/github.com/billgraziano/dpapi
-// [AES-GCM]: https://wikipedia.org/wiki/Galois/Counter_Mode
-func encrypt(data []byte) *[]byte {
+func nativeEncrypt(data []byte) (*[]byte, error) {
+	defer zero(&data)
+
+	h := vaultHeader{Version: VAULT_VERSION, KDF: KDF_NONE, Cipher: CIPHER_DPAPI}
+
 	encrypted, err := dpapi.EncryptBytes(data)
-	checkError(err)
+	if err != nil {
+		return nil, err
+	}
 
-	zero(&data)
+	encrypted = append(h.marshal(), encrypted...)
 
-	return &encrypted
+	return &encrypted, nil
 }
 
-// Decrypts some data and returns a pointer pointing to the decrypted byte slice.
-// Also zeros the given data.
+// Decrypts some data written by the native backend and returns a pointer pointing to the
+// decrypted byte slice. Also zeros the given data. Legacy (headerless) data written by older
+// versions of PwdMan can still be decrypted.
 // Note: On Windows, this function will benefit from Windows's [Data Protection API (DPAPI)]
-// by using [billgraziano]'s [Go wrapper]. On Linux, this function will utilize the
-// symmetric [AES-GCM] cipher. The AES key will be the SHA-256 hash of the data stored in
-// /etc/machine-id. This is implemented using the Go packages [crypto/aes] and [crypto/cipher].
+// by using [billgraziano]'s [Go wrapper]. On Linux, this function only decrypts legacy data.
 //
 // [Data Protection API (DPAPI)]: https://wikipedia.org/wiki/Data_Protection_API
 // [billgraziano]: https://github.com/billgraziano
 // [Go wrapper]: https://pkg.go.dev/github.com/billgraziano/dpapi
-// [AES-GCM]: https://wikipedia.org/wiki/Galois/Counter_Mode
-func decrypt(data []byte) *[]byte {
-	decrypted, err := dpapi.DecryptBytes(data)
-	checkError(err)
+func nativeDecrypt(data []byte) (*[]byte, error) {
+	defer zero(&data)
+
+	encrypted := data
+
+	if isFramedVault(data) {
+		h, _, body, err := parseVault(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if h.Cipher != CIPHER_DPAPI {
+			return nil, fmt.Errorf("%w (cipher %d)", ErrUnsupportedCipher, h.Cipher)
+		}
+
+		encrypted = body
+	}
 
-	zero(&data)
+	// The DPAPI fails if the data has been encrypted by another Windows account or has been modified
+	decrypted, err := dpapi.DecryptBytes(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrWrongKey, err)
+	}
 
-	return &decrypted
+	return &decrypted, nil
 }